@@ -2,15 +2,19 @@ package main
 
 import (
 	"BeRoHuTe/config"
+	"BeRoHuTe/internal/alerts"
 	"BeRoHuTe/internal/buttons"
 	"BeRoHuTe/internal/buttons/rpi"
+	"BeRoHuTe/internal/contracts"
 	"BeRoHuTe/internal/data_clean"
 	"BeRoHuTe/internal/handler"
 	"BeRoHuTe/internal/sensor"
+	"BeRoHuTe/internal/ventilation"
 	"BeRoHuTe/internal/weather"
 	"BeRoHuTe/util"
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 	"log"
@@ -34,8 +38,73 @@ func main() {
 	templateDir := util.GetEnv("TEMPLATE_DIR", "./web")
 
 	weatherReadInterval := util.GetEnvInt("WEATHER_READ_INTERVAL_MIN", 30) // in minutes
+	weatherProvider := util.GetEnv("WEATHER_PROVIDER", "openweather")      // openweather, openmeteo
+	weatherBaseURL := util.GetEnv("WEATHER_BASE_URL", "")
+	weatherTimeout := util.GetEnvInt("WEATHER_TIMEOUT", 10)                         // in seconds
+	weatherBackoffInitial := util.GetEnvInt("WEATHER_BACKOFF_INITIAL", 2)           // in seconds
+	weatherBackoffMax := util.GetEnvInt("WEATHER_BACKOFF_MAX", weatherReadInterval) // in minutes
+	forecastRetention := util.GetEnvInt("WEATHER_FORECAST_RETENTION", 30)           // in days
+	weatherAlerts := util.GetEnvBool("WEATHER_ALERTS", true)
+	weatherLanguage := util.GetEnv("WEATHER_LANGUAGE", "de")
+	displayUnits := util.GetEnv("DISPLAY_UNITS", "metric") // metric, imperial
 	openWeatherApiKey := util.GetEnv("OPEN_WEATHER_API_KEY", "")
 	locationCoords := util.GetEnv("LOCATION_COORDS", "")
+	weatherLocations := util.GetEnv("WEATHER_LOCATIONS", "") // e.g. "Home:48.14:11.58,Cabin:47.42:10.98:60"
+	sensorList := util.GetEnv("SENSORS", "")                 // e.g. "1:Living room:living:sensor1,2:Bedroom:bedroom:sensor2"
+	buttonList := util.GetEnv("BUTTONS", "1:24")             // e.g. "1:24:Bedroom window:bedroom:2,2:25:Kitchen window:kitchen:1"
+	debounceConfig := buttons.DebounceConfig{
+		SampleRate:    time.Duration(util.GetEnvInt("BUTTON_SAMPLE_RATE", 10)) * time.Millisecond,
+		StableTime:    time.Duration(util.GetEnvInt("BUTTON_STABLE_TIME", 50)) * time.Millisecond,
+		EdgeDetection: util.GetEnvBool("BUTTON_EDGE_DETECTION", false),
+	}
+	gestureConfig := buttons.GestureConfig{
+		LongPress:   time.Duration(util.GetEnvInt("BUTTON_LONG_PRESS", 1000)) * time.Millisecond,
+		DoublePress: time.Duration(util.GetEnvInt("BUTTON_DOUBLE_PRESS", 400)) * time.Millisecond,
+	}
+	sessionTimeout := time.Duration(util.GetEnvInt("BUTTON_SESSION_TIMEOUT", 180)) * time.Minute
+	sessionRules := buttons.SessionRules{
+		MinDuration: time.Duration(util.GetEnvInt("BUTTON_SESSION_MIN_DURATION", 5)) * time.Second,
+		MaxDuration: time.Duration(util.GetEnvInt("BUTTON_SESSION_MAX_DURATION", 180)) * time.Minute,
+		MinGap:      time.Duration(util.GetEnvInt("BUTTON_SESSION_MIN_GAP", 10)) * time.Second,
+	}
+	gestureList := util.GetEnv("BUTTON_GESTURES", "short=toggle_session,double=annotate:shower,long=acknowledge_alerts")
+
+	// reading validation, DHT22 range is -40-80°C and 0-100% but reports 99.9% on errors
+	minTemperature := util.GetEnvFloat("SENSOR_MIN_TEMPERATURE", -40)
+	maxTemperature := util.GetEnvFloat("SENSOR_MAX_TEMPERATURE", 80)
+	minHumidity := util.GetEnvFloat("SENSOR_MIN_HUMIDITY", 0)
+	maxHumidity := util.GetEnvFloat("SENSOR_MAX_HUMIDITY", 99.5)
+	maxTemperatureRate := util.GetEnvFloat("SENSOR_MAX_TEMPERATURE_RATE", 5) // °C per minute
+	maxHumidityRate := util.GetEnvFloat("SENSOR_MAX_HUMIDITY_RATE", 20)      // % per minute
+	medianWindow := util.GetEnvInt("SENSOR_MEDIAN_WINDOW", 1)                // 1 = disabled
+	staleAfter := util.GetEnvInt("SENSOR_STALE_AFTER", 300)                  // in seconds, 0 = disabled
+	staleAlert := util.GetEnvBool("SENSOR_STALE_ALERT", false)
+
+	sensorSources := util.GetEnv("SENSOR_SOURCE", "redis") // comma separated: redis (polling the keys), redis-stream, mqtt
+	redisStream := util.GetEnv("REDIS_STREAM", "sensors")
+	redisStreamGroup := util.GetEnv("REDIS_STREAM_GROUP", "backend")
+	redisStreamConsumer := util.GetEnv("REDIS_STREAM_CONSUMER", "backend")
+	mqttConfig := sensor.MQTTConfig{
+		Broker:           util.GetEnv("MQTT_BROKER", "tcp://localhost:1883"),
+		ClientID:         util.GetEnv("MQTT_CLIENT_ID", "rasp-temp-humid"),
+		Username:         util.GetEnv("MQTT_USERNAME", ""),
+		Password:         util.GetEnv("MQTT_PASSWORD", ""),
+		TemperatureField: util.GetEnv("MQTT_TEMPERATURE_FIELD", "temperature"),
+		HumidityField:    util.GetEnv("MQTT_HUMIDITY_FIELD", "humidity"),
+		TimestampField:   util.GetEnv("MQTT_TIMESTAMP_FIELD", ""),
+	}
+	mqttTopics := util.GetEnv("MQTT_TOPICS", "")   // e.g. "home/bedroom/climate=3,home/attic/climate=4"
+	pushTokens := util.GetEnv("PUSH_TOKENS", "")   // e.g. "attic=secret1,garage=secret2"
+	pushSensors := util.GetEnv("PUSH_SENSORS", "") // e.g. "attic=4,garage=5,garage=6"
+
+	ventilationMinDifference := util.GetEnvFloat("VENTILATION_MIN_DIFFERENCE", 1) // g/m³ drier outside
+	ventilationTargetHumidity := util.GetEnvFloat("VENTILATION_TARGET_HUMIDITY", 50)
+	ventilationMinTemperature := util.GetEnvFloat("VENTILATION_MIN_TEMPERATURE", 16)
+	ventilationMaxWeatherAge := util.GetEnvInt("VENTILATION_MAX_WEATHER_AGE", 180) // in minutes
+	ventilationMaxIndoorAge := util.GetEnvInt("VENTILATION_MAX_INDOOR_AGE", 60)    // in minutes
+	ventilationWeatherLocation := util.GetEnv("VENTILATION_WEATHER_LOCATION", "")
+	analysisBefore := util.GetEnvInt("VENTILATION_ANALYSIS_BEFORE", 10) // in minutes
+	analysisAfter := util.GetEnvInt("VENTILATION_ANALYSIS_AFTER", 60)   // in minutes
 
 	progArgs, err := config.GetProgramArgs()
 	if err != nil {
@@ -57,6 +126,22 @@ func main() {
 		}
 	}
 
+	locations, err := weather.ParseLocations(weatherLocations, time.Duration(weatherReadInterval)*time.Minute)
+	if err != nil {
+		log.Fatalf("Failed to parse weather locations: %v", err)
+	}
+	if len(locations) == 0 {
+		locations = []weather.Location{{
+			Name:      weather.DefaultLocation,
+			Latitude:  locationLat,
+			Longitude: locationLon,
+			Interval:  time.Duration(weatherReadInterval) * time.Minute,
+		}}
+	}
+	if ventilationWeatherLocation == "" {
+		ventilationWeatherLocation = locations[0].Name
+	}
+
 	// init db
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
@@ -78,57 +163,242 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize repository: %v", err)
 	}
+	registry, err := sensor.NewRegistry(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize sensor registry: %v", err)
+	}
+	if strings.TrimSpace(sensorList) != "" {
+		sensors, err := sensor.ParseSensors(sensorList)
+		if err != nil {
+			log.Fatalf("Failed to parse sensors: %v", err)
+		}
+		if err := registry.Sync(sensors); err != nil {
+			log.Fatalf("Failed to store sensors: %v", err)
+		}
+	}
+	calibrationRepo, err := sensor.NewCalibrationRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize calibration repository: %v", err)
+	}
 	btnRepo, err := buttons.NewButtonRepository(db)
 	if err != nil {
 		log.Fatalf("Failed to initialize button repository: %v", err)
 	}
-	weatherRepo, err := weather.NewWeatherRepository(db)
+	locationNames := make([]string, len(locations))
+	for i, location := range locations {
+		locationNames[i] = location.Name
+	}
+	weatherRepo, err := weather.NewWeatherRepository(db, weather.WithLocations(locationNames...))
 	if err != nil {
 		log.Fatalf("Failed to initialize weather repository: %v", err)
 	}
+	forecastRepo, err := weather.NewForecastRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize weather forecast repository: %v", err)
+	}
+	weatherAlertRepo, err := weather.NewAlertRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize weather alert repository: %v", err)
+	}
+	analysisRepo, err := ventilation.NewAnalysisRepository(db)
+	if err != nil {
+		log.Fatalf("Failed to initialize ventilation analysis repository: %v", err)
+	}
 
 	// Initialize sensors
-	sensorService := sensor.NewDHTSensors(rdb)
-	btnService := rpi.NewButtonService(24)
-	weatherService := weather.NewOpenWeatherService(
-		openWeatherApiKey,
-		locationLat,
-		locationLon)
+	var sensorServices []sensor.Service
+	for _, source := range strings.Split(sensorSources, ",") {
+		switch strings.TrimSpace(source) {
+		case "redis":
+			sensorServices = append(sensorServices, sensor.NewDHTSensors(rdb, registry))
+		case "redis-stream":
+			streamSensors, err := sensor.NewRedisStreamSensors(rdb, registry, redisStream, redisStreamGroup, redisStreamConsumer)
+			if err != nil {
+				log.Fatalf("Failed to initialize redis stream: %v", err)
+			}
+			sensorServices = append(sensorServices, streamSensors)
+		case "mqtt":
+			mqttConfig.Topics, err = sensor.ParseTopics(mqttTopics)
+			if err != nil {
+				log.Fatalf("Failed to parse MQTT topics: %v", err)
+			}
+			mqttSensors, err := sensor.NewMQTTSensors(registry, mqttConfig)
+			if err != nil {
+				log.Fatalf("Failed to connect to MQTT broker: %v", err)
+			}
+			defer mqttSensors.Close()
+			sensorServices = append(sensorServices, mqttSensors)
+		default:
+			log.Fatalf("Unknown SENSOR_SOURCE %q", source)
+		}
+	}
+	var sensorService sensor.Service = sensor.NewMultiService(sensorServices...)
+	if len(sensorServices) == 1 {
+		sensorService = sensorServices[0]
+	}
+	buttonConfigs, err := buttons.ParseButtons(buttonList)
+	if err != nil {
+		log.Fatalf("Failed to parse buttons: %v", err)
+	}
+	btnServices := make(map[int]buttons.Service)
+	for _, button := range buttonConfigs {
+		btnServices[button.ID], err = rpi.NewButtonService(button.Pin, debounceConfig, gestureConfig)
+		if err != nil {
+			log.Fatalf("Failed to initialize button %d: %v", button.ID, err)
+		}
+	}
+	weatherOptions := []weather.ServiceOption{
+		weather.WithTimeout(time.Duration(weatherTimeout) * time.Second),
+		weather.WithLanguage(weatherLanguage),
+	}
+	if weatherBaseURL != "" {
+		weatherOptions = append(weatherOptions, weather.WithBaseURL(weatherBaseURL))
+	}
+	weatherServices := make([]weather.Service, len(locations))
+	for i, location := range locations {
+		weatherServices[i], err = weather.NewService(weatherProvider, openWeatherApiKey, location.Latitude,
+			location.Longitude, weatherOptions...)
+		if err != nil {
+			log.Fatalf("Failed to initialize weather provider for %s: %v", location.Name, err)
+		}
+	}
 
 	///////////////////////// Applications /////////////////////////
 
 	ctx := context.Background()
 	defer ctx.Done()
 
-	dhtApp := sensor.NewApp(time.Duration(readInterval)*time.Second, sensorService, repo)
+	alertBus := alerts.NewBus()
+
+	calibrator := sensor.NewCalibrator(calibrationRepo, repo)
+	pipeline := sensor.NewPipeline(
+		sensor.NewRangeFilter(minTemperature, maxTemperature, minHumidity, maxHumidity),
+		sensor.NewRateFilter(maxTemperatureRate, maxHumidityRate),
+		sensor.NewMedianFilter(medianWindow),
+	)
+	sensorOptions := []sensor.AppOption{
+		sensor.WithRegistry(registry),
+		sensor.WithCalibrator(calibrator),
+		sensor.WithPipeline(pipeline),
+	}
+	if staleAfter > 0 {
+		monitor := sensor.NewStalenessMonitor(time.Duration(staleAfter) * time.Second)
+		if staleAlert {
+			monitor.OnStale(func(sensorID int, lastFresh time.Time) {
+				name := fmt.Sprintf("Sensor %d", sensorID)
+				if s, err := registry.Get(sensorID); err == nil {
+					name = s.Name
+				}
+				message := "No reading received yet"
+				if !lastFresh.IsZero() {
+					message = fmt.Sprintf("No new values since %s", lastFresh.Format("2006-01-02 15:04:05"))
+				}
+				alertBus.Raise(contracts.Alert{
+					Key:      fmt.Sprintf("sensor-stale-%d", sensorID),
+					Source:   "sensor",
+					Severity: alerts.SeverityWarning,
+					Title:    fmt.Sprintf("%s is stale", name),
+					Message:  message,
+				})
+			})
+			monitor.OnFresh(func(sensorID int) {
+				alertBus.Resolve(fmt.Sprintf("sensor-stale-%d", sensorID))
+			})
+		}
+		sensorOptions = append(sensorOptions, sensor.WithStalenessMonitor(monitor))
+	}
+	dhtApp, err := sensor.NewApp(time.Duration(readInterval)*time.Second, sensorService, repo, sensorOptions...)
+	if err != nil {
+		log.Fatalf("Failed to initialize sensor application: %v", err)
+	}
 	dhtApp.Start(ctx, true)
 	defer dhtApp.Stop()
 
-	btnApp, err := buttons.NewButtonApp(btnService, btnRepo)
+	gestureActions, err := buttons.ParseGestures(gestureList)
 	if err != nil {
-		log.Fatalf("Failed to initialize button application: %v", err)
+		log.Fatalf("Failed to parse button gestures: %v", err)
+	}
+	var btnApps []*buttons.ButtonApp
+	for _, button := range buttonConfigs {
+		btnOptions := []buttons.ButtonAppOption{
+			buttons.WithSessionTimeout(sessionTimeout),
+			buttons.WithSessionRules(sessionRules),
+		}
+		if button.Mode == buttons.ModePush {
+			btnOptions = append(btnOptions, buttons.WithGestures(gestureActions), buttons.WithAlertAcknowledger(alertBus))
+		}
+		btnApp, err := buttons.NewButtonApp(button.ID, btnServices[button.ID], btnRepo, btnOptions...)
+		if err != nil {
+			log.Fatalf("Failed to initialize button application: %v", err)
+		}
+		if err := btnApp.Start(ctx); err != nil {
+			log.Fatalf("Failed to start button %d: %v", button.ID, err)
+		}
+		btnApps = append(btnApps, btnApp)
 	}
-	if err := btnApp.Start(ctx); err != nil {
-		log.Fatalf("Failed to start button application: %v", err)
+
+	weatherAppOptions := []weather.AppOption{
+		weather.WithBackoff(time.Duration(weatherBackoffInitial)*time.Second,
+			time.Duration(weatherBackoffMax)*time.Minute),
+		weather.WithForecasts(forecastRepo, time.Duration(forecastRetention)*24*time.Hour),
+	}
+	if weatherAlerts {
+		weatherAppOptions = append(weatherAppOptions, weather.WithAlerts(weatherAlertRepo))
+	}
+	for i, location := range locations {
+		weatherApp, err := weather.NewApp(location.Name, weatherServices[i], weatherRepo, weatherAppOptions...)
+		if err != nil {
+			log.Fatalf("Failed to initialize weather application for %s: %v", location.Name, err)
+		}
+		weatherApp.OnAlert(func(alert contracts.WeatherAlert) {
+			alertBus.Raise(contracts.Alert{
+				Key:      fmt.Sprintf("weather-alert-%d", alert.ID),
+				Source:   "weather",
+				Severity: alert.Severity,
+				Title:    fmt.Sprintf("%s: %s", alert.Name, alert.Event),
+				Message: fmt.Sprintf("%s - %s %s", alert.StartAt.Format("2006-01-02 15:04"),
+					alert.EndAt.Format("2006-01-02 15:04"), alert.Sender),
+			})
+		})
+		weatherApp.OnAlertEnded(func(alert contracts.WeatherAlert) {
+			alertBus.Resolve(fmt.Sprintf("weather-alert-%d", alert.ID))
+		})
+		weatherApp.Start(ctx, location.Interval)
+		defer weatherApp.Stop()
 	}
 
-	weatherApp := weather.NewApp(weatherService, weatherRepo)
-	weatherApp.Start(ctx, time.Duration(weatherReadInterval)*time.Minute)
-	defer weatherApp.Stop()
+	analyzer, err := ventilation.NewAnalyzer(btnRepo, repo, analysisRepo,
+		ventilation.WithWindows(time.Duration(analysisBefore)*time.Minute, time.Duration(analysisAfter)*time.Minute),
+		ventilation.WithButtonSensors(buttons.SensorsByButton(buttonConfigs)))
+	if err != nil {
+		log.Fatalf("Failed to initialize ventilation analysis: %v", err)
+	}
+	analyzer.Start(ctx, 5*time.Minute)
+	defer analyzer.Stop()
 
 	if progArgs.Cleanup {
 		dataCleanUp, err := data_clean.NewApp(btnRepo, repo,
+			// the cleanup deletes the readings around the ventilations, analyze them first
+			data_clean.WithMinAge(analyzer.AfterWindow()),
+			data_clean.WithButtonSensors(buttons.SensorsByButton(buttonConfigs)),
 			data_clean.WithBeforeCleanUp(func() error {
-				dhtApp.Stop()
-				if err := btnApp.Stop(); err != nil {
+				if _, err := analyzer.AnalyzePending(); err != nil {
 					return err
 				}
+				dhtApp.Stop()
+				for _, btnApp := range btnApps {
+					if err := btnApp.Stop(); err != nil {
+						return err
+					}
+				}
 				return nil
 			}),
 			data_clean.WithAfterCleanUp(func() error {
 				dhtApp.Start(ctx, false)
-				if err := btnApp.Start(ctx); err != nil {
-					return err
+				for _, btnApp := range btnApps {
+					if err := btnApp.Start(ctx); err != nil {
+						return err
+					}
 				}
 				return nil
 			}),
@@ -140,8 +410,41 @@ func main() {
 		defer dataCleanUp.Stop()
 	}
 
+	recommender, err := ventilation.NewRecommender(repo, weatherRepo,
+		ventilation.WithMinDifference(ventilationMinDifference),
+		ventilation.WithTargetHumidity(ventilationTargetHumidity),
+		ventilation.WithMinIndoorTemperature(ventilationMinTemperature),
+		ventilation.WithMaxWeatherAge(time.Duration(ventilationMaxWeatherAge)*time.Minute),
+		ventilation.WithMaxIndoorAge(time.Duration(ventilationMaxIndoorAge)*time.Minute),
+		ventilation.WithSensorRegistry(registry),
+		ventilation.WithWeatherLocation(ventilationWeatherLocation))
+	if err != nil {
+		log.Fatalf("Failed to initialize ventilation recommender: %v", err)
+	}
+
 	// Initialize HTTP handler
-	h, err := handler.New(repo, templateDir, btnRepo, weatherRepo)
+	nodeTokens, err := handler.ParseTokens(pushTokens)
+	if err != nil {
+		log.Fatalf("Failed to parse push tokens: %v", err)
+	}
+	nodeSensors, err := handler.ParsePushSensors(pushSensors)
+	if err != nil {
+		log.Fatalf("Failed to parse push sensors: %v", err)
+	}
+	h, err := handler.New(repo, templateDir, btnRepo, weatherRepo,
+		handler.WithSensorRegistry(registry),
+		handler.WithButtons(buttonConfigs),
+		handler.WithCalibrator(calibrator),
+		handler.WithSensorStatus(dhtApp),
+		handler.WithAlerts(alertBus),
+		handler.WithPushIngestion(dhtApp, nodeTokens, nodeSensors),
+		handler.WithVentilationRecommender(recommender),
+		handler.WithButtonEvents(btnRepo),
+		handler.WithForecasts(forecastRepo, ventilationWeatherLocation),
+		handler.WithWeatherAlerts(weatherAlertRepo),
+		handler.WithUnits(displayUnits),
+		handler.WithVentilationEvents(btnRepo),
+		handler.WithVentilationAnalyses(analysisRepo))
 	if err != nil {
 		log.Fatalf("Failed to initialize handler: %v", err)
 	}
@@ -149,6 +452,23 @@ func main() {
 	// Setup routes
 	http.HandleFunc("/", h.ServeIndex)
 	http.HandleFunc("/api/data", h.ServeAPI)
+	http.HandleFunc("GET /api/calibrations", h.ServeCalibrations)
+	http.HandleFunc("POST /api/calibrations", h.CreateCalibration)
+	http.HandleFunc("GET /api/quarantine", h.ServeQuarantine)
+	http.HandleFunc("GET /api/alerts", h.ServeAlerts)
+	http.HandleFunc("GET /api/mold-risk", h.ServeMoldRisk)
+	http.HandleFunc("GET /api/ventilation", h.ServeVentilation)
+	http.HandleFunc("GET /api/ventilation/analyses", h.ServeVentilationAnalyses)
+	http.HandleFunc("GET /api/ventilation/events", h.ServeVentilationEvents)
+	http.HandleFunc("POST /api/ventilation/events", h.CreateVentilationEvent)
+	http.HandleFunc("PUT /api/ventilation/events/{id}", h.UpdateVentilationEvent)
+	http.HandleFunc("DELETE /api/ventilation/events/{id}", h.DeleteVentilationEvent)
+	http.HandleFunc("GET /api/button-events", h.ServeButtonEvents)
+	http.HandleFunc("GET /api/weather/forecast", h.ServeForecast)
+	http.HandleFunc("GET /api/weather/history", h.ServeWeatherHistory)
+	http.HandleFunc("GET /api/weather/averages", h.ServeWeatherAverages)
+	http.HandleFunc("GET /api/weather/alerts", h.ServeWeatherAlerts)
+	http.HandleFunc("POST /api/readings", h.IngestReadings)
 
 	// Start server
 	log.Printf("Starting server on port %s, reading sensors every %d seconds", port, readInterval)
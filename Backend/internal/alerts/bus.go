@@ -0,0 +1,100 @@
+package alerts
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Bus keeps the currently active alerts and notifies the subscribers about new ones
+type Bus struct {
+	mu           sync.RWMutex
+	active       map[string]contracts.Alert
+	acknowledged map[string]bool
+	subscribers  []func(alert contracts.Alert)
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		active:       map[string]contracts.Alert{},
+		acknowledged: map[string]bool{},
+		subscribers:  make([]func(alert contracts.Alert), 0),
+	}
+}
+
+// Subscribe registers a function called for every newly raised alert
+func (b *Bus) Subscribe(fn func(alert contracts.Alert)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, fn)
+}
+
+// Raise activates an alert, subscribers are only notified if the key is not active or acknowledged yet
+func (b *Bus) Raise(alert contracts.Alert) {
+	if alert.RaisedAt.IsZero() {
+		alert.RaisedAt = time.Now()
+	}
+
+	b.mu.Lock()
+	if _, ok := b.active[alert.Key]; ok || b.acknowledged[alert.Key] {
+		b.mu.Unlock()
+		return
+	}
+	b.active[alert.Key] = alert
+	subscribers := append([]func(alert contracts.Alert){}, b.subscribers...)
+	b.mu.Unlock()
+
+	log.Printf("[Alert] %s: %s", alert.Title, alert.Message)
+	for _, fn := range subscribers {
+		fn(alert)
+	}
+}
+
+// Resolve deactivates the alert with the given key, it can be raised again afterwards
+func (b *Bus) Resolve(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.active, key)
+	delete(b.acknowledged, key)
+}
+
+// Acknowledge hides all active alerts until they are resolved, returns the number of acknowledged alerts
+func (b *Bus) Acknowledge() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := len(b.active)
+	for key := range b.active {
+		b.acknowledged[key] = true
+		delete(b.active, key)
+	}
+	if count > 0 {
+		log.Printf("[Alert] Acknowledged %d alerts", count)
+	}
+	return count
+}
+
+// Active returns all active alerts, the newest first
+func (b *Bus) Active() []contracts.Alert {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := make([]contracts.Alert, 0, len(b.active))
+	for _, alert := range b.active {
+		result = append(result, alert)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RaisedAt.After(result[j].RaisedAt)
+	})
+	return result
+}
@@ -1,69 +1,319 @@
 package buttons
 
 import (
+	"BeRoHuTe/internal/contracts"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
+type AlertAcknowledger interface {
+	Acknowledge() int
+}
+
+type ButtonAppOption func(*ButtonApp) error
+
+// WithGestures handles the button as push button: the gestures trigger the mapped actions
+// instead of a ventilation lasting while the button is open
+func WithGestures(actions map[Gesture]string) ButtonAppOption {
+	return func(b *ButtonApp) error {
+		if len(actions) == 0 {
+			return errors.New("no gesture actions configured")
+		}
+		b.gestures = actions
+		return nil
+	}
+}
+
+// WithAlertAcknowledger is used by the acknowledge_alerts action
+func WithAlertAcknowledger(acknowledger AlertAcknowledger) ButtonAppOption {
+	return func(b *ButtonApp) error {
+		b.acknowledger = acknowledger
+		return nil
+	}
+}
+
+// WithSessionTimeout finishes sessions that are still in progress after the timeout, e.g. a missed release
+func WithSessionTimeout(timeout time.Duration) ButtonAppOption {
+	return func(b *ButtonApp) error {
+		if timeout <= 0 {
+			return errors.New("session timeout must be positive")
+		}
+		b.timeout = timeout
+		return nil
+	}
+}
+
+// WithSessionRules rejects sessions that are too short, too long or too close to the previous session
+func WithSessionRules(rules SessionRules) ButtonAppOption {
+	return func(b *ButtonApp) error {
+		if rules.MinDuration < 0 || rules.MaxDuration < 0 || rules.MinGap < 0 {
+			return errors.New("session rules must not be negative")
+		}
+		if rules.MaxDuration > 0 && rules.MaxDuration < rules.MinDuration {
+			return errors.New("maximum session duration is shorter than the minimum")
+		}
+		b.rules = rules
+		return nil
+	}
+}
+
+// sessionCheckInterval is how often a session in progress is checked for a timeout
+const sessionCheckInterval = 10 * time.Second
+
+// ButtonApp records the ventilation sessions of a single button. A session is stored as soon as it starts,
+// so it is resumed after a restart.
 type ButtonApp struct {
-	service Service
-	repo    ButtonRepository
+	buttonID     int
+	service      Service
+	repo         ButtonRepository
+	gestures     map[Gesture]string
+	acknowledger AlertAcknowledger
+	timeout      time.Duration
+	rules        SessionRules
 
-	startsAt time.Time
-	endsAt   time.Time
+	mu      sync.Mutex
+	session *contracts.ButtonReading
+	resumed bool
+	// lastEndAt is the end of the last accepted session, for the minimum gap
+	lastEndAt time.Time
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
 }
 
-func NewButtonApp(service Service, repo ButtonRepository) (*ButtonApp, error) {
-	return &ButtonApp{
-		service: service,
-		repo:    repo,
+func NewButtonApp(buttonID int, service Service, repo ButtonRepository, options ...ButtonAppOption) (*ButtonApp, error) {
+	app := &ButtonApp{
+		buttonID: buttonID,
+		service:  service,
+		repo:     repo,
+		timeout:  3 * time.Hour,
+	}
+
+	for _, option := range options {
+		if err := option(app); err != nil {
+			return nil, err
+		}
+	}
 
-		startsAt: time.Time{},
-		endsAt:   time.Time{},
-	}, nil
+	// a session finished by the timeout lasts as long as the timeout
+	if app.rules.MaxDuration > 0 && app.timeout > app.rules.MaxDuration {
+		return nil, fmt.Errorf("session timeout %v is longer than the maximum session duration %v, "+
+			"every timed out session would be rejected", app.timeout, app.rules.MaxDuration)
+	}
+
+	return app, nil
 }
 
+// Start resumes the session in progress of the button and starts listening to the button
 func (b *ButtonApp) Start(ctx context.Context) error {
-	b.service.OnPush(b.buttonPushed)
-	b.service.OnRelease(b.buttonReleased)
+	b.mu.Lock()
+	if b.cancel != nil {
+		b.mu.Unlock()
+		return nil
+	}
+
+	session, err := b.repo.GetOpenSession(b.buttonID)
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	last, err := b.repo.GetLastAccepted(b.buttonID)
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	if last != nil {
+		b.lastEndAt = *last.EndAt
+	}
+
+	b.session = session
+	b.resumed = session != nil
+	if b.resumed {
+		log.Printf("button %d resumes session %d started at %v", b.buttonID, session.ID, session.StartAt)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	b.cancel = cancel
+	b.mu.Unlock()
+
+	if b.gestures != nil {
+		b.service.OnGesture(b.gestureRecognized)
+	} else {
+		b.service.OnPush(b.buttonPushed)
+		b.service.OnRelease(b.buttonReleased)
+	}
+
+	if err := b.service.Start(ctx); err != nil {
+		// not running, so Start can be tried again
+		b.mu.Lock()
+		b.cancel = nil
+		b.mu.Unlock()
+		cancel()
+		return err
+	}
 
-	return b.service.Start(ctx, 10*time.Second)
+	b.wg.Add(1)
+	go b.watch(ctx)
+	return nil
 }
 
-func (b *ButtonApp) buttonPushed(_ ButtonState) error {
-	b.startsAt = time.Now()
-	log.Printf("button pushed at %v", b.startsAt)
+func (b *ButtonApp) watch(ctx context.Context) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := b.CheckSession(now); err != nil {
+				log.Printf("Error checking session of button %d: %v", b.buttonID, err)
+			}
+		}
+	}
+}
+
+// CheckSession finishes a session that exceeded the timeout and a resumed session of a contact
+// that was closed while the app was stopped
+func (b *ButtonApp) CheckSession(now time.Time) error {
+	// read before taking the lock, so the lock is never held while waiting for the lock of the service
+	state, stateErr := b.service.GetCurrentState()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.session == nil {
+		return nil
+	}
+
+	if now.Sub(b.session.StartAt) >= b.timeout {
+		log.Printf("button %d session %d timed out after %v", b.buttonID, b.session.ID, b.timeout)
+		return b.finish(b.session.StartAt.Add(b.timeout))
+	}
+
+	// a push button session is finished by the next toggle
+	if !b.resumed || b.gestures != nil {
+		return nil
+	}
+
+	if stateErr != nil || state == ButtonStateUnknown {
+		return stateErr
+	}
+	b.resumed = false
+	if state == ButtonStateClosed {
+		log.Printf("button %d was closed while stopped, finishing session %d", b.buttonID, b.session.ID)
+		return b.finish(now)
+	}
 	return nil
 }
 
-func (b *ButtonApp) buttonReleased(_ ButtonState) error {
-	b.endsAt = time.Now()
-	if b.startsAt.IsZero() {
-		return fmt.Errorf("button released but never pushed at %v", b.endsAt)
+// gestureRecognized executes the action mapped to the gesture and stores the event
+func (b *ButtonApp) gestureRecognized(gesture Gesture, at time.Time) error {
+	action, ok := b.gestures[gesture]
+	if !ok {
+		action = ActionNone
 	}
-	if b.endsAt.Before(b.startsAt) {
-		return fmt.Errorf("button release cannot be before pushing it")
+	name, label, _ := strings.Cut(action, ":")
+	log.Printf("button %d %s press at %v: %s", b.buttonID, gesture, at, action)
+
+	var actionErr error
+	switch name {
+	case ActionToggleSession:
+		if b.inSession() {
+			actionErr = b.buttonReleased(ButtonStateClosed, at)
+		} else {
+			actionErr = b.buttonPushed(ButtonStateOpen, at)
+		}
+	case ActionAcknowledgeAlerts:
+		if b.acknowledger == nil {
+			actionErr = errors.New("no alerts to acknowledge configured")
+		} else {
+			b.acknowledger.Acknowledge()
+		}
 	}
-	if b.startsAt.Sub(b.endsAt).Seconds() > 5 {
-		return fmt.Errorf("button release too frequent (5 seconds between start and end)")
+
+	err := b.repo.SaveEvent(contracts.ButtonEvent{
+		ButtonID: b.buttonID,
+		Gesture:  string(gesture),
+		Action:   name,
+		Label:    label,
+		At:       at,
+	})
+	return errors.Join(actionErr, err)
+}
+
+func (b *ButtonApp) inSession() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.session != nil
+}
+
+func (b *ButtonApp) buttonPushed(_ ButtonState, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.session != nil {
+		log.Printf("button %d pushed at %v, session %d is still in progress since %v", b.buttonID, at,
+			b.session.ID, b.session.StartAt)
+		return nil
 	}
 
-	err := b.repo.Save(1, b.startsAt, b.endsAt)
+	id, err := b.repo.StartSession(b.buttonID, at)
 	if err != nil {
 		return err
 	}
+	b.session = &contracts.ButtonReading{ID: id, ButtonID: b.buttonID, StartAt: at}
+	log.Printf("button %d pushed at %v", b.buttonID, at)
+	return nil
+}
+
+func (b *ButtonApp) buttonReleased(_ ButtonState, at time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.session == nil {
+		return fmt.Errorf("button %d released but never pushed at %v", b.buttonID, at)
+	}
+	return b.finish(at)
+}
+
+// finish stores the end of the session in progress, a session violating the rules is stored as rejected.
+// The caller must hold the lock.
+func (b *ButtonApp) finish(endAt time.Time) error {
+	reason := b.rules.Validate(b.session.StartAt, endAt, b.lastEndAt)
+	if err := b.repo.FinishSession(b.session.ID, endAt, reason); err != nil {
+		return err
+	}
 
-	log.Println("Button pushed and released [", b.startsAt, ",", b.endsAt, "]")
+	if reason != "" {
+		log.Printf("button %d session %d [%v, %v] rejected: %s", b.buttonID, b.session.ID, b.session.StartAt,
+			endAt, reason)
+	} else {
+		log.Println("Button", b.buttonID, "pushed and released [", b.session.StartAt, ",", endAt, "]")
+		b.lastEndAt = endAt
+	}
 
-	b.startsAt = time.Time{}
-	b.endsAt = time.Time{}
+	b.session = nil
+	b.resumed = false
 	return nil
 }
 
+// Stop stops listening to the button, a session in progress stays stored and is resumed by Start
 func (b *ButtonApp) Stop() error {
-	b.startsAt = time.Time{}
-	b.endsAt = time.Time{}
+	b.mu.Lock()
+	cancel := b.cancel
+	b.cancel = nil
+	b.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		b.wg.Wait()
+	}
 	return b.service.Stop()
 }
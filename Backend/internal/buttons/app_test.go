@@ -0,0 +1,210 @@
+package buttons
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// fakeService reports a fixed state, Start fails with the scripted errors
+type fakeService struct {
+	startErrs []error
+	starts    int
+	state     ButtonState
+}
+
+func (f *fakeService) Start(ctx context.Context) error {
+	var err error
+	if f.starts < len(f.startErrs) {
+		err = f.startErrs[f.starts]
+	}
+	f.starts++
+	return err
+}
+
+func (f *fakeService) GetCurrentState() (ButtonState, error)                    { return f.state, nil }
+func (f *fakeService) OnPush(fn func(state ButtonState, at time.Time) error)    {}
+func (f *fakeService) OnRelease(fn func(state ButtonState, at time.Time) error) {}
+func (f *fakeService) OnGesture(fn func(gesture Gesture, at time.Time) error)   {}
+func (f *fakeService) Stop() error                                              { return nil }
+
+func newTestButtonRepository(t *testing.T) ButtonRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewButtonRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestButtonAppStartAfterFailedServiceStart(t *testing.T) {
+	service := &fakeService{startErrs: []error{errors.New("pin busy")}}
+	app, err := NewButtonApp(1, service, newTestButtonRepository(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Start(context.Background()); err == nil {
+		t.Fatal("expected the error of the service")
+	}
+	if app.cancel != nil {
+		t.Error("the app counts as running after the service failed to start")
+	}
+
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("unexpected error starting again: %v", err)
+	}
+	if service.starts != 2 {
+		t.Errorf("expected the service to be started again, got %d starts", service.starts)
+	}
+	if err := app.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestButtonAppCheckSession(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		// zero without a session in progress
+		startedAt time.Time
+		state     ButtonState
+		// zero if the session stays in progress
+		wantEnd time.Time
+	}{
+		{name: "no session", state: ButtonStateClosed},
+		{name: "in progress", startedAt: now.Add(-time.Hour), state: ButtonStateOpen},
+		{
+			name:      "timed out",
+			startedAt: now.Add(-4 * time.Hour),
+			state:     ButtonStateOpen,
+			wantEnd:   now.Add(-4 * time.Hour).Add(3 * time.Hour),
+		},
+		{
+			name:      "closed while stopped",
+			startedAt: now.Add(-10 * time.Minute),
+			state:     ButtonStateClosed,
+			wantEnd:   now,
+		},
+		{name: "state not known yet", startedAt: now.Add(-10 * time.Minute), state: ButtonStateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestButtonRepository(t)
+			var id int64
+			if !tt.startedAt.IsZero() {
+				var err error
+				if id, err = repo.StartSession(1, tt.startedAt); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			app, err := NewButtonApp(1, &fakeService{state: tt.state}, repo, WithSessionTimeout(3*time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := app.Start(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { app.Stop() })
+
+			if err := app.CheckSession(now); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			open, err := repo.GetOpenSession(1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantEnd.IsZero() {
+				if !tt.startedAt.IsZero() && (open == nil || open.ID != id) {
+					t.Errorf("expected session %d to stay in progress, got %+v", id, open)
+				}
+				return
+			}
+
+			if open != nil {
+				t.Fatalf("expected the session to be finished, still in progress since %v", open.StartAt)
+			}
+			session, err := repo.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.EndAt == nil || !session.EndAt.Equal(tt.wantEnd) {
+				t.Errorf("expected the session to end at %v, got %v", tt.wantEnd, session.EndAt)
+			}
+		})
+	}
+}
+
+// a callback waits for the lock of its app while the app asks the service for the current state, holding its lock
+func TestPinServiceCallbacksRunWithoutTheLock(t *testing.T) {
+	pin := &scriptedPin{script: "co"}
+	service, err := NewPinService(pin, DebounceConfig{SampleRate: time.Millisecond}, GestureConfig{LongPress: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stands in for the lock of the app
+	var appMu sync.Mutex
+	entered := make(chan bool)
+	service.OnPush(func(ButtonState, time.Time) error {
+		entered <- true
+		appMu.Lock()
+		defer appMu.Unlock()
+		return nil
+	})
+
+	start := time.Now()
+	service.Sample(start)
+	pin.sample = 1
+
+	appMu.Lock()
+	sampled := make(chan bool)
+	go func() {
+		service.Sample(start.Add(time.Millisecond))
+		close(sampled)
+	}()
+	<-entered
+
+	// a writer waiting for the lock of the service blocks new readers of a read-write mutex
+	registered := make(chan bool)
+	go func() {
+		service.OnRelease(func(ButtonState, time.Time) error { return nil })
+		close(registered)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	state := make(chan ButtonState)
+	go func() {
+		current, _ := service.GetCurrentState()
+		state <- current
+	}()
+	select {
+	case current := <-state:
+		if current != ButtonStateOpen {
+			t.Errorf("expected the open state, got %s", current)
+		}
+	case <-time.After(time.Second):
+		t.Error("reading the state blocked while a callback waited for the lock of its app")
+	}
+
+	appMu.Unlock()
+	<-sampled
+	<-registered
+}
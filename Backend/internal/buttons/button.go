@@ -0,0 +1,86 @@
+package buttons
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ModeContact = "contact"
+	ModePush    = "push"
+)
+
+// ParseButtons parses the button definitions in the format "id:pin[:name[:room[:sensor_id[:mode]]]],..."
+func ParseButtons(value string) ([]*contracts.Button, error) {
+	var buttons []*contracts.Button
+	seen := map[int]bool{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 || len(fields) > 6 {
+			return nil, fmt.Errorf("invalid button definition %q, expected id:pin[:name[:room[:sensor_id[:mode]]]]", entry)
+		}
+
+		id, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid button ID in %q: %v", entry, err)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate button ID %d", id)
+		}
+		seen[id] = true
+
+		pin, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPIO pin in %q: %v", entry, err)
+		}
+
+		button := &contracts.Button{
+			ID:   id,
+			Pin:  pin,
+			Name: fmt.Sprintf("Window %d", id),
+			Mode: ModeContact,
+		}
+		if len(fields) > 2 && strings.TrimSpace(fields[2]) != "" {
+			button.Name = strings.TrimSpace(fields[2])
+		}
+		if len(fields) > 3 {
+			button.Room = strings.TrimSpace(fields[3])
+		}
+		if len(fields) > 4 && strings.TrimSpace(fields[4]) != "" {
+			button.SensorID, err = strconv.Atoi(strings.TrimSpace(fields[4]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid sensor ID in %q: %v", entry, err)
+			}
+		}
+		if len(fields) > 5 && strings.TrimSpace(fields[5]) != "" {
+			button.Mode = strings.TrimSpace(fields[5])
+			if button.Mode != ModeContact && button.Mode != ModePush {
+				return nil, fmt.Errorf("invalid mode %q of button %d, expected %s or %s", button.Mode, id,
+					ModeContact, ModePush)
+			}
+		}
+
+		buttons = append(buttons, button)
+	}
+
+	return buttons, nil
+}
+
+// SensorsByButton returns the associated sensor ID of each button that has one
+func SensorsByButton(buttons []*contracts.Button) map[int]int {
+	sensors := make(map[int]int)
+	for _, button := range buttons {
+		if button.SensorID != 0 {
+			sensors[button.ID] = button.SensorID
+		}
+	}
+	return sensors
+}
@@ -0,0 +1,76 @@
+package buttons
+
+import "time"
+
+// Pin is a digital input, high (open) means the button is pushed or the window is open
+type Pin interface {
+	Read() ButtonState
+}
+
+// EdgePin is a pin that latches level changes in hardware, EdgeDetected reports and clears the latch
+type EdgePin interface {
+	Pin
+	EdgeDetected() bool
+}
+
+type DebounceConfig struct {
+	// SampleRate is the interval between two reads of the pin
+	SampleRate time.Duration
+	// StableTime is how long a new state must hold before it is accepted, shorter changes are contact bounce
+	StableTime time.Duration
+	// EdgeDetection only reads the pin after the hardware detected an edge, requires an EdgePin
+	EdgeDetection bool
+}
+
+// Debouncer accepts a new button state once it was sampled unchanged for the stable time.
+// It does not read the pin itself, so it can be fed with scripted samples.
+type Debouncer struct {
+	stableTime time.Duration
+
+	stable         ButtonState
+	candidate      ButtonState
+	candidateSince time.Time
+}
+
+func NewDebouncer(stableTime time.Duration) *Debouncer {
+	return &Debouncer{
+		stableTime: stableTime,
+		stable:     ButtonStateUnknown,
+		candidate:  ButtonStateUnknown,
+	}
+}
+
+// Update feeds a sample taken at now. If the stable state changed it returns true and the time the new state
+// was first sampled. The first sample only sets the initial state.
+func (d *Debouncer) Update(state ButtonState, now time.Time) (bool, time.Time) {
+	if state == ButtonStateUnknown {
+		return false, time.Time{}
+	}
+
+	if d.stable == ButtonStateUnknown {
+		d.stable = state
+		d.candidate = state
+		return false, time.Time{}
+	}
+
+	if state != d.candidate {
+		d.candidate = state
+		d.candidateSince = now
+	}
+
+	if d.candidate != d.stable && now.Sub(d.candidateSince) >= d.stableTime {
+		d.stable = d.candidate
+		return true, d.candidateSince
+	}
+
+	return false, time.Time{}
+}
+
+// Pending reports whether a state change is being debounced
+func (d *Debouncer) Pending() bool {
+	return d.candidate != d.stable
+}
+
+func (d *Debouncer) State() ButtonState {
+	return d.stable
+}
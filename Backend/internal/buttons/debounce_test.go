@@ -0,0 +1,171 @@
+package buttons
+
+import (
+	"testing"
+	"time"
+)
+
+// scriptedPin returns the scripted state of the current sample, 'o' for open and 'c' for closed,
+// the last state holds after the end of the script
+type scriptedPin struct {
+	script string
+	sample int
+}
+
+func (p *scriptedPin) Read() ButtonState {
+	return p.state(p.sample)
+}
+
+func (p *scriptedPin) state(sample int) ButtonState {
+	return scriptState(p.script[min(sample, len(p.script)-1)])
+}
+
+// scriptedEdgePin latches every change of the scripted state, including bounces that return to the previous state
+type scriptedEdgePin struct {
+	scriptedPin
+	checked int
+}
+
+func (p *scriptedEdgePin) EdgeDetected() bool {
+	detected := false
+	for i := p.checked + 1; i <= p.sample; i++ {
+		detected = detected || p.state(i) != p.state(i-1)
+	}
+	p.checked = p.sample
+	return detected
+}
+
+func scriptState(c byte) ButtonState {
+	if c == 'o' {
+		return ButtonStateOpen
+	}
+	return ButtonStateClosed
+}
+
+type stateChange struct {
+	state ButtonState
+	at    time.Duration
+}
+
+// runScript samples the pin once per millisecond, advance moves the pin to the sample. It returns the debounced
+// changes with their time relative to the first sample.
+func runScript(t *testing.T, pin Pin, advance func(sample int), samples int, stableTime time.Duration) []stateChange {
+	t.Helper()
+	_, edgeDetection := pin.(EdgePin)
+	service, err := NewPinService(pin, DebounceConfig{
+		SampleRate:    time.Millisecond,
+		StableTime:    stableTime,
+		EdgeDetection: edgeDetection,
+	}, GestureConfig{LongPress: time.Second, DoublePress: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 10, 17, 8, 0, 0, 0, time.UTC)
+	var changes []stateChange
+	record := func(state ButtonState, at time.Time) error {
+		changes = append(changes, stateChange{state: state, at: at.Sub(start)})
+		return nil
+	}
+	service.OnPush(record)
+	service.OnRelease(record)
+
+	for i := 0; i < samples; i++ {
+		advance(i)
+		service.Sample(start.Add(time.Duration(i) * time.Millisecond))
+	}
+	return changes
+}
+
+func TestPinServiceDebounce(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []stateChange
+	}{
+		{
+			name:   "bouncing push",
+			script: "cccccocococ" + "oooooooooo",
+			// the push is reported once, at the first sample of the stable open state
+			expected: []stateChange{{ButtonStateOpen, 11 * time.Millisecond}},
+		},
+		{
+			name:     "bouncing push and release",
+			script:   "cccccocoo" + "oooooooooo" + "cocccccccccc",
+			expected: []stateChange{{ButtonStateOpen, 7 * time.Millisecond}, {ButtonStateClosed, 21 * time.Millisecond}},
+		},
+		{
+			name:     "change shorter than the stable time",
+			script:   "ccccc" + "oooo" + "cccccccccc",
+			expected: nil,
+		},
+		{
+			name:     "dropout shorter than the stable time",
+			script:   "ccccc" + "oooooooo" + "ccc" + "oooooooo",
+			expected: []stateChange{{ButtonStateOpen, 5 * time.Millisecond}},
+		},
+		{
+			name:     "change sampled for less than the stable time",
+			script:   "ccccc" + "ooooo" + "ccccccccc",
+			expected: nil,
+		},
+		{
+			name:     "change sampled for the stable time",
+			script:   "ccccc" + "oooooo" + "ccccccccc",
+			expected: []stateChange{{ButtonStateOpen, 5 * time.Millisecond}, {ButtonStateClosed, 11 * time.Millisecond}},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, edge := range []bool{false, true} {
+			name := tt.name
+			scripted := &scriptedPin{script: tt.script}
+			var pin Pin = scripted
+			if edge {
+				name += " with edge detection"
+				edgePin := &scriptedEdgePin{scriptedPin: scriptedPin{script: tt.script}}
+				scripted, pin = &edgePin.scriptedPin, edgePin
+			}
+
+			t.Run(name, func(t *testing.T) {
+				changes := runScript(t, pin, func(sample int) { scripted.sample = sample }, len(tt.script)+10, 5*time.Millisecond)
+				if !equalChanges(changes, tt.expected) {
+					t.Errorf("script %s: expected %v, got %v", tt.script, tt.expected, changes)
+				}
+			})
+		}
+	}
+}
+
+func TestDebouncerInitialState(t *testing.T) {
+	d := NewDebouncer(5 * time.Millisecond)
+	start := time.Now()
+
+	if changed, _ := d.Update(ButtonStateUnknown, start); changed || d.State() != ButtonStateUnknown {
+		t.Fatalf("unknown sample changed the state to %s", d.State())
+	}
+	// the first sample sets the state without reporting a change
+	if changed, _ := d.Update(ButtonStateOpen, start); changed || d.State() != ButtonStateOpen {
+		t.Fatalf("expected the unreported initial state open, got %s", d.State())
+	}
+	if d.Pending() {
+		t.Error("no change should be pending")
+	}
+
+	d.Update(ButtonStateClosed, start.Add(time.Millisecond))
+	if !d.Pending() || d.State() != ButtonStateOpen {
+		t.Errorf("expected a pending change from open, got %s, pending %t", d.State(), d.Pending())
+	}
+}
+
+func equalChanges(a, b []stateChange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package buttons
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Gesture string
+
+const (
+	GestureShort  Gesture = "short"
+	GestureLong   Gesture = "long"
+	GestureDouble Gesture = "double"
+)
+
+const (
+	ActionToggleSession     = "toggle_session"
+	ActionAnnotate          = "annotate"
+	ActionAcknowledgeAlerts = "acknowledge_alerts"
+	ActionNone              = "none"
+)
+
+type GestureConfig struct {
+	// LongPress is the minimum duration of a long press
+	LongPress time.Duration
+	// DoublePress is the maximum pause between two short presses of a double press,
+	// a short press is reported once this time passed without a second press
+	DoublePress time.Duration
+}
+
+// GestureEvent is a recognized gesture, At is the time the (first) press started
+type GestureEvent struct {
+	Gesture Gesture
+	At      time.Time
+}
+
+// gestureRecognizer turns the debounced presses of a button into gestures, it is driven by the PinService samples
+type gestureRecognizer struct {
+	config GestureConfig
+
+	pressed     bool
+	pressedAt   time.Time
+	lastRelease time.Time
+
+	pendingShort bool
+	pendingAt    time.Time
+}
+
+func (g *gestureRecognizer) Push(at time.Time) []GestureEvent {
+	events := g.Tick(at)
+	g.pressed = true
+	g.pressedAt = at
+	return events
+}
+
+func (g *gestureRecognizer) Release(at time.Time) []GestureEvent {
+	if !g.pressed {
+		return nil
+	}
+	g.pressed = false
+
+	if at.Sub(g.pressedAt) >= g.config.LongPress {
+		events := g.flush()
+		return append(events, GestureEvent{Gesture: GestureLong, At: g.pressedAt})
+	}
+
+	if g.pendingShort {
+		g.pendingShort = false
+		return []GestureEvent{{Gesture: GestureDouble, At: g.pendingAt}}
+	}
+
+	if g.config.DoublePress <= 0 {
+		return []GestureEvent{{Gesture: GestureShort, At: g.pressedAt}}
+	}
+
+	g.pendingShort = true
+	g.pendingAt = g.pressedAt
+	g.lastRelease = at
+	return nil
+}
+
+// Tick reports a pending short press once no second press followed within the double press time
+func (g *gestureRecognizer) Tick(now time.Time) []GestureEvent {
+	if g.pendingShort && !g.pressed && now.Sub(g.lastRelease) > g.config.DoublePress {
+		return g.flush()
+	}
+	return nil
+}
+
+func (g *gestureRecognizer) flush() []GestureEvent {
+	if !g.pendingShort {
+		return nil
+	}
+	g.pendingShort = false
+	return []GestureEvent{{Gesture: GestureShort, At: g.pendingAt}}
+}
+
+// ParseGestures parses the gesture actions in the format "gesture=action,...",
+// e.g. "short=toggle_session,double=annotate:shower,long=acknowledge_alerts"
+func ParseGestures(value string) (map[Gesture]string, error) {
+	actions := make(map[Gesture]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		gesture, action, ok := strings.Cut(entry, "=")
+		gesture, action = strings.TrimSpace(gesture), strings.TrimSpace(action)
+		if !ok {
+			return nil, fmt.Errorf("invalid gesture definition %q, expected gesture=action", entry)
+		}
+
+		switch Gesture(gesture) {
+		case GestureShort, GestureLong, GestureDouble:
+		default:
+			return nil, fmt.Errorf("unknown gesture %q", gesture)
+		}
+
+		name, label, _ := strings.Cut(action, ":")
+		switch name {
+		case ActionToggleSession, ActionAcknowledgeAlerts, ActionNone:
+		case ActionAnnotate:
+			if strings.TrimSpace(label) == "" {
+				return nil, errors.New("annotate needs a label, e.g. annotate:shower")
+			}
+		default:
+			return nil, fmt.Errorf("unknown action %q", action)
+		}
+
+		actions[Gesture(gesture)] = action
+	}
+	return actions, nil
+}
@@ -0,0 +1,149 @@
+package buttons
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// gestureStep pushes ('p'), releases ('r') or ticks ('t') the recognizer at a millisecond after the start
+type gestureStep struct {
+	kind byte
+	at   int
+}
+
+type gestureAt struct {
+	gesture Gesture
+	at      int
+}
+
+func TestGestureRecognizer(t *testing.T) {
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	config := GestureConfig{LongPress: time.Second, DoublePress: 300 * time.Millisecond}
+
+	tests := []struct {
+		name   string
+		config GestureConfig
+		steps  []gestureStep
+		want   []gestureAt
+	}{
+		{
+			name:   "short press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'t', 401}},
+			want:   []gestureAt{{GestureShort, 0}},
+		},
+		{
+			name:   "short press waits for a second press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'t', 400}},
+		},
+		{
+			name:   "double press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'p', 250}, {'r', 350}, {'t', 1000}},
+			want:   []gestureAt{{GestureDouble, 0}},
+		},
+		{
+			name:   "long press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 1000}, {'t', 2000}},
+			want:   []gestureAt{{GestureLong, 0}},
+		},
+		{
+			name:   "held but released before the long press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 999}, {'t', 1300}},
+			want:   []gestureAt{{GestureShort, 0}},
+		},
+		{
+			name:   "short press followed by a long press",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'p', 200}, {'r', 1300}},
+			want:   []gestureAt{{GestureShort, 0}, {GestureLong, 200}},
+		},
+		{
+			name:   "two short presses too far apart",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'p', 500}, {'r', 600}, {'t', 1000}},
+			want:   []gestureAt{{GestureShort, 0}, {GestureShort, 500}},
+		},
+		{
+			name:   "no pending short press while held",
+			config: config,
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'p', 300}, {'t', 800}, {'r', 900}},
+			want:   []gestureAt{{GestureDouble, 0}},
+		},
+		{
+			name:   "double press disabled",
+			config: GestureConfig{LongPress: time.Second},
+			steps:  []gestureStep{{'p', 0}, {'r', 100}, {'p', 200}, {'r', 300}},
+			want:   []gestureAt{{GestureShort, 0}, {GestureShort, 200}},
+		},
+		{
+			name:   "release without a push",
+			config: config,
+			steps:  []gestureStep{{'r', 100}, {'t', 1000}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recognizer := &gestureRecognizer{config: tt.config}
+			var got []gestureAt
+			for _, step := range tt.steps {
+				at := start.Add(time.Duration(step.at) * time.Millisecond)
+				var events []GestureEvent
+				switch step.kind {
+				case 'p':
+					events = recognizer.Push(at)
+				case 'r':
+					events = recognizer.Release(at)
+				case 't':
+					events = recognizer.Tick(at)
+				}
+				for _, event := range events {
+					got = append(got, gestureAt{event.Gesture, int(event.At.Sub(start).Milliseconds())})
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gestures %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGestures(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[Gesture]string
+		wantErr bool
+	}{
+		{
+			name:  "all gestures",
+			value: "short=toggle_session, double=annotate:shower,long=acknowledge_alerts",
+			want: map[Gesture]string{GestureShort: ActionToggleSession, GestureDouble: "annotate:shower",
+				GestureLong: ActionAcknowledgeAlerts},
+		},
+		{name: "empty", value: "", want: map[Gesture]string{}},
+		{name: "disabled gesture", value: "long=none", want: map[Gesture]string{GestureLong: ActionNone}},
+		{name: "missing action", value: "short", wantErr: true},
+		{name: "unknown gesture", value: "triple=toggle_session", wantErr: true},
+		{name: "unknown action", value: "short=open_window", wantErr: true},
+		{name: "annotation without a label", value: "double=annotate", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGestures(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGestures() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGestures() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,14 +2,27 @@ package buttons
 
 import (
 	"BeRoHuTe/internal/contracts"
+	"BeRoHuTe/util"
 	"database/sql"
+	"fmt"
 	"time"
 )
 
 type ButtonRepository interface {
-	Save(buttonID int, startAt time.Time, endAt time.Time) error
+	Get(id int64) (*contracts.ButtonReading, error)
+	Create(reading contracts.ButtonReading) (int64, error)
+	Update(reading contracts.ButtonReading) error
+	Delete(id int64) error
+	GetOverlapping(buttonID int, startAt time.Time, endAt time.Time, excludeID int64) ([]*contracts.ButtonReading, error)
+	StartSession(buttonID int, startAt time.Time) (int64, error)
+	FinishSession(id int64, endAt time.Time, rejectReason string) error
+	GetOpenSession(buttonID int) (*contracts.ButtonReading, error)
+	GetLastAccepted(buttonID int) (*contracts.ButtonReading, error)
+	GetRejected(offset int, limit int) ([]*contracts.ButtonReading, error)
 	GetLatest() ([]*contracts.ButtonReading, error)
 	GetAll(offset int, limit int) ([]*contracts.ButtonReading, error)
+	SaveEvent(event contracts.ButtonEvent) error
+	GetEvents(offset int, limit int) ([]*contracts.ButtonEvent, error)
 }
 
 type buttonRepository struct {
@@ -35,28 +48,189 @@ func (r *buttonRepository) createTable() error {
 	    id INTEGER PRIMARY KEY AUTOINCREMENT,
 	    button_id INTEGER NOT NULL,
 	    start_at DATETIME NOT NULL,
-	    end_at DATETIME NOT NULL
+	    end_at DATETIME,
+	    source TEXT NOT NULL DEFAULT 'gpio',
+	    rejected BOOLEAN NOT NULL DEFAULT 0,
+	    reject_reason TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE IF NOT EXISTS button_events (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    button_id INTEGER NOT NULL,
+	    gesture TEXT NOT NULL,
+	    action TEXT NOT NULL,
+	    label TEXT NOT NULL,
+	    at DATETIME NOT NULL
 	)`
 	_, err := r.db.Exec(query)
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := r.migrateNullableEndAt(); err != nil {
+		return err
+	}
+	if err := util.AddColumnIfMissing(r.db, "button_readings", "source", "TEXT NOT NULL DEFAULT 'gpio'"); err != nil {
+		return err
+	}
+	if err := util.AddColumnIfMissing(r.db, "button_readings", "rejected", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return util.AddColumnIfMissing(r.db, "button_readings", "reject_reason", "TEXT NOT NULL DEFAULT ''")
+}
+
+// migrateNullableEndAt drops the NOT NULL constraint of end_at from older databases,
+// sqlite can only change a constraint by copying the table
+func (r *buttonRepository) migrateNullableEndAt() error {
+	var notNull bool
+	err := r.db.QueryRow(`SELECT "notnull" FROM pragma_table_info('button_readings') WHERE name = 'end_at'`).Scan(&notNull)
+	if err != nil || !notNull {
+		return err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	CREATE TABLE button_readings_new (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    button_id INTEGER NOT NULL,
+	    start_at DATETIME NOT NULL,
+	    end_at DATETIME
+	);
+	INSERT INTO button_readings_new (id, button_id, start_at, end_at)
+	SELECT id, button_id, start_at, end_at FROM button_readings;
+	DROP TABLE button_readings;
+	ALTER TABLE button_readings_new RENAME TO button_readings`
+	if _, err := tx.Exec(query); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// GetAll returns the finished and accepted sessions, the newest first
 func (r *buttonRepository) GetAll(offset int, limit int) ([]*contracts.ButtonReading, error) {
-	query := `SELECT * FROM button_readings ORDER BY id DESC LIMIT ? OFFSET ?`
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings
+	WHERE end_at IS NOT NULL AND rejected = 0
+	ORDER BY id DESC LIMIT ? OFFSET ?`
+	return r.queryReadings(query, limit, offset)
+}
+
+// GetRejected returns the sessions rejected by the session rules, the newest first
+func (r *buttonRepository) GetRejected(offset int, limit int) ([]*contracts.ButtonReading, error) {
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings
+	WHERE rejected = 1
+	ORDER BY id DESC LIMIT ? OFFSET ?`
 	return r.queryReadings(query, limit, offset)
 }
 
-func (r *buttonRepository) Save(buttonID int, startAt time.Time, endAt time.Time) error {
-	query := `INSERT INTO button_readings (button_id, start_at, end_at) VALUES (?, ?, ?)`
-	_, err := r.db.Exec(query, buttonID, startAt, endAt)
+// Get returns a session or nil if it does not exist
+func (r *buttonRepository) Get(id int64) (*contracts.ButtonReading, error) {
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings WHERE id = ?`
+	readings, err := r.queryReadings(query, id)
+	if err != nil || len(readings) == 0 {
+		return nil, err
+	}
+	return readings[0], nil
+}
+
+// Create stores a finished session and returns its ID
+func (r *buttonRepository) Create(reading contracts.ButtonReading) (int64, error) {
+	query := `INSERT INTO button_readings (button_id, start_at, end_at, source, rejected, reject_reason)
+	VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := r.db.Exec(query, reading.ButtonID, reading.StartAt, reading.EndAt, reading.Source, reading.Rejected,
+		reading.RejectReason)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (r *buttonRepository) Update(reading contracts.ButtonReading) error {
+	query := `UPDATE button_readings SET button_id = ?, start_at = ?, end_at = ?, source = ?, rejected = ?, reject_reason = ?
+	WHERE id = ?`
+	_, err := r.db.Exec(query, reading.ButtonID, reading.StartAt, reading.EndAt, reading.Source, reading.Rejected,
+		reading.RejectReason, reading.ID)
 	return err
 }
 
+func (r *buttonRepository) Delete(id int64) error {
+	_, err := r.db.Exec(`DELETE FROM button_readings WHERE id = ?`, id)
+	return err
+}
+
+// GetOverlapping returns the accepted sessions of a button overlapping [startAt, endAt] except excludeID,
+// a session in progress overlaps everything after its start
+func (r *buttonRepository) GetOverlapping(buttonID int, startAt time.Time, endAt time.Time, excludeID int64) ([]*contracts.ButtonReading, error) {
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings
+	WHERE button_id = ? AND id != ? AND rejected = 0 AND start_at < ? AND (end_at IS NULL OR end_at > ?)
+	ORDER BY start_at`
+	return r.queryReadings(query, buttonID, excludeID, endAt, startAt)
+}
+
+// StartSession stores the start of a session in progress and returns its ID
+func (r *buttonRepository) StartSession(buttonID int, startAt time.Time) (int64, error) {
+	query := `INSERT INTO button_readings (button_id, start_at, end_at, source) VALUES (?, ?, NULL, ?)`
+	result, err := r.db.Exec(query, buttonID, startAt, contracts.SourceGPIO)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// FinishSession stores the end of a session in progress, a session with a reject reason is stored as rejected
+func (r *buttonRepository) FinishSession(id int64, endAt time.Time, rejectReason string) error {
+	query := `UPDATE button_readings SET end_at = ?, rejected = ?, reject_reason = ? WHERE id = ? AND end_at IS NULL`
+	result, err := r.db.Exec(query, endAt, rejectReason != "", rejectReason, id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no session %d in progress", id)
+	}
+	return nil
+}
+
+// GetOpenSession returns the latest session in progress of a button or nil
+func (r *buttonRepository) GetOpenSession(buttonID int) (*contracts.ButtonReading, error) {
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings WHERE button_id = ? AND end_at IS NULL
+	ORDER BY start_at DESC LIMIT 1`
+	readings, err := r.queryReadings(query, buttonID)
+	if err != nil || len(readings) == 0 {
+		return nil, err
+	}
+	return readings[0], nil
+}
+
+// GetLastAccepted returns the latest finished and accepted session of a button or nil
+func (r *buttonRepository) GetLastAccepted(buttonID int) (*contracts.ButtonReading, error) {
+	query := `SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings
+	WHERE button_id = ? AND end_at IS NOT NULL AND rejected = 0
+	ORDER BY end_at DESC LIMIT 1`
+	readings, err := r.queryReadings(query, buttonID)
+	if err != nil || len(readings) == 0 {
+		return nil, err
+	}
+	return readings[0], nil
+}
+
+// GetLatest returns the latest accepted session of each button, including sessions in progress
 func (r *buttonRepository) GetLatest() ([]*contracts.ButtonReading, error) {
-	query := `SELECT * FROM button_readings ORDER BY start_at DESC LIMIT 1`
+	query := `
+	SELECT id, button_id, start_at, end_at, source, rejected, reject_reason FROM button_readings
+	WHERE rejected = 0 AND (button_id, start_at) IN (
+		SELECT button_id, MAX(start_at)
+		FROM button_readings
+		WHERE rejected = 0
+		GROUP BY button_id
+	)
+	ORDER BY button_id
+	`
 	readings, err := r.queryReadings(query)
 	if err != nil {
 		return nil, err
@@ -78,12 +252,46 @@ func (r *buttonRepository) queryReadings(query string, args ...interface{}) ([]*
 	var readings []*contracts.ButtonReading
 	for rows.Next() {
 		var reading contracts.ButtonReading
-		err := rows.Scan(&reading.ID, &reading.ButtonID, &reading.StartAt, &reading.EndAt)
+		var endAt sql.NullTime
+		err := rows.Scan(&reading.ID, &reading.ButtonID, &reading.StartAt, &endAt, &reading.Source, &reading.Rejected,
+			&reading.RejectReason)
 		if err != nil {
 			return nil, err
 		}
+		if endAt.Valid {
+			reading.EndAt = util.Ptr(endAt.Time)
+		}
 		readings = append(readings, &reading)
 	}
 
 	return readings, rows.Err()
 }
+
+// SaveEvent stores a gesture of a push button
+func (r *buttonRepository) SaveEvent(event contracts.ButtonEvent) error {
+	query := `INSERT INTO button_events (button_id, gesture, action, label, at) VALUES (?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, event.ButtonID, event.Gesture, event.Action, event.Label, event.At)
+	return err
+}
+
+// GetEvents returns the gestures of the push buttons, the newest first
+func (r *buttonRepository) GetEvents(offset int, limit int) ([]*contracts.ButtonEvent, error) {
+	query := `SELECT id, button_id, gesture, action, label, at FROM button_events ORDER BY at DESC LIMIT ? OFFSET ?`
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*contracts.ButtonEvent
+	for rows.Next() {
+		var event contracts.ButtonEvent
+		err := rows.Scan(&event.ID, &event.ButtonID, &event.Gesture, &event.Action, &event.Label, &event.At)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
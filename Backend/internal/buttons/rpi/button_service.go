@@ -3,140 +3,85 @@ package rpi
 import (
 	"BeRoHuTe/internal/buttons"
 	"context"
-	"fmt"
 	"github.com/stianeikeland/go-rpio/v4"
-	"log"
-	"time"
 )
 
 // IMPORTANT must be in a separate package, so we can compile on a dev environment not having support on go-rpio library
 
-type ButtonService struct {
-	pin      rpio.Pin
-	start    bool
-	stop     chan bool
-	pinState buttons.ButtonState
-
-	onPushFns    []func(state buttons.ButtonState) error
-	onReleaseFns []func(state buttons.ButtonState) error
-}
-
-func NewButtonService(pin int) buttons.Service {
-	if pin == 0 {
-		pin = 24
-	}
-
-	rpin := rpio.Pin(pin)
-
-	return &ButtonService{
-		pin:          rpin,
-		stop:         make(chan bool),
-		pinState:     buttons.ButtonStateUnknown,
-		onPushFns:    make([]func(state buttons.ButtonState) error, 0),
-		onReleaseFns: make([]func(state buttons.ButtonState) error, 0),
-	}
+// gpioPin reads a rpio pin, high is open (button pushed / window open)
+type gpioPin struct {
+	pin rpio.Pin
 }
 
-func (b *ButtonService) GetCurrentState() (buttons.ButtonState, error) {
-	switch b.pin.Read() {
+func (p gpioPin) Read() buttons.ButtonState {
+	switch p.pin.Read() {
 	case rpio.High:
-		return buttons.ButtonStateOpen, nil
+		return buttons.ButtonStateOpen
 	case rpio.Low:
-		return buttons.ButtonStateClosed, nil
+		return buttons.ButtonStateClosed
 	default:
-		return buttons.ButtonStateUnknown, nil
+		return buttons.ButtonStateUnknown
 	}
 }
 
-func (b *ButtonService) OnPush(fn func(state buttons.ButtonState) error) {
-	b.onPushFns = append(b.onPushFns, fn)
+func (p gpioPin) EdgeDetected() bool {
+	return p.pin.EdgeDetected()
 }
 
-func (b *ButtonService) OnRelease(fn func(state buttons.ButtonState) error) {
-	b.onReleaseFns = append(b.onReleaseFns, fn)
+type ButtonService struct {
+	*buttons.PinService
+	pin    rpio.Pin
+	config buttons.DebounceConfig
+	start  bool
 }
 
-func (b *ButtonService) Start(ctx context.Context, dur time.Duration) error {
-	if b.start == true {
-		return nil
+func NewButtonService(pin int, config buttons.DebounceConfig, gestures buttons.GestureConfig) (buttons.Service, error) {
+	if pin == 0 {
+		pin = 24
 	}
 
-	if err := rpio.Open(); err != nil {
-		return err
+	rpin := rpio.Pin(pin)
+	pinService, err := buttons.NewPinService(gpioPin{pin: rpin}, config, gestures)
+	if err != nil {
+		return nil, err
 	}
 
-	b.pin.Input()
-
-	b.start = true
-
-	ticker := time.NewTicker(dur)
-
-	b.stop = make(chan bool)
-	go func() {
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-b.stop:
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				err := b.listenToEdge()
-				if err != nil {
-					log.Println("ButtonService error: ", err)
-				}
-			default:
-			}
-		}
-	}()
-	return nil
+	return &ButtonService{
+		PinService: pinService,
+		pin:        rpin,
+		config:     config,
+	}, nil
 }
 
-func (b *ButtonService) listenToEdge() error {
-	var err error
-
-	newState, err := b.GetCurrentState()
-	if err != nil {
-		return fmt.Errorf("cannot get current state: %v", err)
+func (b *ButtonService) Start(ctx context.Context) error {
+	if b.start == true {
+		return nil
 	}
 
-	if newState == buttons.ButtonStateUnknown {
-		return fmt.Errorf("new button state should be either 1 or 0")
+	if err := openGPIO(); err != nil {
+		return err
 	}
 
-	if b.pinState == buttons.ButtonStateClosed && newState == buttons.ButtonStateOpen { // low to high edge = push
-		b.executeFns(true, false)
-	} else if b.pinState == buttons.ButtonStateOpen && newState == buttons.ButtonStateClosed { // high to low edge = release
-		b.executeFns(false, true)
+	b.pin.Input()
+	if b.config.EdgeDetection {
+		b.pin.Detect(rpio.AnyEdge)
 	}
 
-	b.pinState = newState
-
-	return nil
+	b.start = true
+	return b.PinService.Start(ctx)
 }
 
-func (b *ButtonService) executeFns(push, release bool) {
-	if push {
-		for _, fn := range b.onPushFns {
-			err := fn(buttons.ButtonStateOpen)
-			if err != nil {
-				log.Println("ButtonService error: ", err)
-			}
-		}
-	}
-	if release {
-		for _, fn := range b.onReleaseFns {
-			err := fn(buttons.ButtonStateClosed)
-			if err != nil {
-				log.Println("ButtonService error: ", err)
-			}
-		}
+func (b *ButtonService) Stop() error {
+	if err := b.PinService.Stop(); err != nil {
+		return err
 	}
-}
 
-func (b *ButtonService) Stop() error {
-	b.onPushFns = make([]func(state buttons.ButtonState) error, 0)
-	b.onReleaseFns = make([]func(state buttons.ButtonState) error, 0)
+	if !b.start {
+		return nil
+	}
+	if b.config.EdgeDetection {
+		b.pin.Detect(rpio.NoEdge)
+	}
 	b.start = false
-	return rpio.Close()
+	return closeGPIO()
 }
@@ -0,0 +1,40 @@
+package rpi
+
+import (
+	"sync"
+
+	"github.com/stianeikeland/go-rpio/v4"
+)
+
+// rpio maps the GPIO memory once for the whole process, the button services share it
+var (
+	gpioMu    sync.Mutex
+	gpioUsers int
+)
+
+func openGPIO() error {
+	gpioMu.Lock()
+	defer gpioMu.Unlock()
+
+	if gpioUsers == 0 {
+		if err := rpio.Open(); err != nil {
+			return err
+		}
+	}
+	gpioUsers++
+	return nil
+}
+
+func closeGPIO() error {
+	gpioMu.Lock()
+	defer gpioMu.Unlock()
+
+	if gpioUsers == 0 {
+		return nil
+	}
+	gpioUsers--
+	if gpioUsers == 0 {
+		return rpio.Close()
+	}
+	return nil
+}
@@ -2,7 +2,6 @@ package buttons
 
 import (
 	"context"
-	"fmt"
 	"math/rand/v2"
 	"sync"
 	"time"
@@ -18,128 +17,40 @@ const (
 
 var allBtnStates = []ButtonState{ButtonStateOpen, ButtonStateClosed}
 
+// Service reports the debounced state changes of a button, at is the time the change was first sampled,
+// and the gestures of the presses, at is the time the (first) press started
 type Service interface {
-	Start(ctx context.Context, dur time.Duration) error
+	Start(ctx context.Context) error
 	GetCurrentState() (ButtonState, error)
-	OnPush(fn func(state ButtonState) error)
-	OnRelease(fn func(state ButtonState) error)
+	OnPush(fn func(state ButtonState, at time.Time) error)
+	OnRelease(fn func(state ButtonState, at time.Time) error)
+	OnGesture(fn func(gesture Gesture, at time.Time) error)
 	Stop() error
 }
 
-type DummyService struct {
-	gpioPin      int
-	onPush       []func(state ButtonState) error
-	onRelease    []func(state ButtonState) error
-	currentState ButtonState
+// randomPin simulates a GPIO pin that picks a random state every interval
+type randomPin struct {
+	interval time.Duration
 
-	mu     sync.RWMutex
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	mu       sync.Mutex
+	state    ButtonState
+	changeAt time.Time
 }
 
-func NewDummyService(gpioPin int) Service {
-	return &DummyService{
-		gpioPin:      gpioPin,
-		onPush:       make([]func(state ButtonState) error, 0),
-		onRelease:    make([]func(state ButtonState) error, 0),
-		currentState: ButtonStateUnknown,
-	}
-}
-
-// Start begins listening to button state changes
-func (s *DummyService) Start(ctx context.Context, dur time.Duration) error {
-	s.ctx, s.cancel = context.WithCancel(ctx)
-	s.wg.Add(1)
-	go s.listenToButton(dur)
-	return nil
-}
-
-func (s *DummyService) listenToButton(rate time.Duration) {
-	defer s.wg.Done()
-
-	ticker := time.NewTicker(rate)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-ticker.C:
-			newState, err := s.readState()
-			if err != nil {
-				continue
-			}
+func (p *randomPin) Read() ButtonState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-			s.mu.Lock()
-			previousState := s.currentState
-			s.currentState = newState
-			s.mu.Unlock()
-
-			// Detect state changes and trigger callbacks
-			if previousState != newState {
-				s.handleStateChange(previousState, newState)
-			}
-		}
+	if now := time.Now(); now.After(p.changeAt) {
+		p.state = allBtnStates[rand.IntN(len(allBtnStates))]
+		p.changeAt = now.Add(p.interval)
 	}
+	return p.state
 }
 
-func (s *DummyService) readState() (ButtonState, error) {
-	// Simulate reading from GPIO pin
-	ran := rand.IntN(2)
-	return allBtnStates[ran], nil
-}
-
-func (s *DummyService) handleStateChange(oldState, newState ButtonState) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Button was pushed (transitioned to closed)
-	if oldState == ButtonStateOpen && newState == ButtonStateClosed {
-		for _, fn := range s.onPush {
-			if err := fn(newState); err != nil {
-				fmt.Printf("Error in onPush callback: %v\n", err)
-			}
-		}
-	}
-
-	// Button was released (transitioned to open)
-	if oldState == ButtonStateClosed && newState == ButtonStateOpen {
-		for _, fn := range s.onRelease {
-			if err := fn(newState); err != nil {
-				fmt.Printf("Error in onRelease callback: %v\n", err)
-			}
-		}
-	}
-}
-
-func (s *DummyService) Stop() error {
-	s.cancel()  // Signal goroutine to stop
-	s.wg.Wait() // Wait for goroutine to finish
-	s.onPush = make([]func(state ButtonState) error, 0)
-	s.onRelease = make([]func(state ButtonState) error, 0)
-	return nil
-}
-
-func (s *DummyService) GetCurrentState() (ButtonState, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	return s.currentState, nil
-}
-
-func (s *DummyService) OnPush(fn func(state ButtonState) error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.onPush = append(s.onPush, fn)
-	return
-}
-
-func (s *DummyService) OnRelease(fn func(state ButtonState) error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	s.onRelease = append(s.onRelease, fn)
-	return
+// NewDummyService simulates a button on the GPIO pin that changes its state randomly every 10 seconds,
+// the simulated pin has no edge detection
+func NewDummyService(gpioPin int, config DebounceConfig, gestures GestureConfig) (Service, error) {
+	config.EdgeDetection = false
+	return NewPinService(&randomPin{interval: 10 * time.Second}, config, gestures)
 }
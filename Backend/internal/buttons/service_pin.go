@@ -0,0 +1,199 @@
+package buttons
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// PinService samples a pin, debounces it and reports a push on a change to open and a release on a change to closed.
+// The presses are also recognized as short, long and double press gestures.
+type PinService struct {
+	pin        Pin
+	config     DebounceConfig
+	debouncer  *Debouncer
+	recognizer *gestureRecognizer
+
+	onPush    []func(state ButtonState, at time.Time) error
+	onRelease []func(state ButtonState, at time.Time) error
+	onGesture []func(gesture Gesture, at time.Time) error
+
+	mu     sync.RWMutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewPinService(pin Pin, config DebounceConfig, gestures GestureConfig) (*PinService, error) {
+	if config.SampleRate <= 0 {
+		return nil, errors.New("sample rate must be positive")
+	}
+	if config.StableTime < 0 {
+		return nil, errors.New("stable time must not be negative")
+	}
+	if _, ok := pin.(EdgePin); config.EdgeDetection && !ok {
+		return nil, errors.New("edge detection is not supported by the pin")
+	}
+	if gestures.LongPress <= 0 {
+		return nil, errors.New("long press duration must be positive")
+	}
+
+	return &PinService{
+		pin:        pin,
+		config:     config,
+		debouncer:  NewDebouncer(config.StableTime),
+		recognizer: &gestureRecognizer{config: gestures},
+	}, nil
+}
+
+func (s *PinService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return nil
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go s.listen(ctx)
+	return nil
+}
+
+func (s *PinService) listen(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.SampleRate)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.Sample(now)
+		}
+	}
+}
+
+// Sample reads the pin once and triggers the callbacks if the debounced state changed or a gesture was recognized
+func (s *PinService) Sample(now time.Time) {
+	s.mu.Lock()
+	gestures := s.recognizer.Tick(now)
+	previous := s.debouncer.State()
+	if s.config.EdgeDetection && previous != ButtonStateUnknown && !s.debouncer.Pending() {
+		// nothing changed since the last sample
+		if !s.pin.(EdgePin).EdgeDetected() {
+			s.mu.Unlock()
+			s.handleGestures(gestures)
+			return
+		}
+	}
+
+	changed, at := s.debouncer.Update(s.pin.Read(), now)
+	state := s.debouncer.State()
+	if changed && state == ButtonStateOpen {
+		gestures = append(gestures, s.recognizer.Push(at)...)
+	} else if changed && state == ButtonStateClosed {
+		gestures = append(gestures, s.recognizer.Release(at)...)
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.handleStateChange(previous, state, at)
+	}
+	s.handleGestures(gestures)
+}
+
+// handleGestures calls the callbacks without holding the lock, they take the locks of their apps, which may in
+// turn ask for the current state
+func (s *PinService) handleGestures(gestures []GestureEvent) {
+	if len(gestures) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	onGesture := s.onGesture
+	s.mu.RUnlock()
+
+	for _, gesture := range gestures {
+		for _, fn := range onGesture {
+			if err := fn(gesture.Gesture, gesture.At); err != nil {
+				log.Printf("Error in onGesture callback: %v", err)
+			}
+		}
+	}
+}
+
+// handleStateChange calls the callbacks without holding the lock like handleGestures
+func (s *PinService) handleStateChange(oldState, newState ButtonState, at time.Time) {
+	s.mu.RLock()
+	onPush, onRelease := s.onPush, s.onRelease
+	s.mu.RUnlock()
+
+	// Button was pushed (transitioned to open)
+	if oldState == ButtonStateClosed && newState == ButtonStateOpen {
+		for _, fn := range onPush {
+			if err := fn(newState, at); err != nil {
+				log.Printf("Error in onPush callback: %v", err)
+			}
+		}
+	}
+
+	// Button was released (transitioned to closed)
+	if oldState == ButtonStateOpen && newState == ButtonStateClosed {
+		for _, fn := range onRelease {
+			if err := fn(newState, at); err != nil {
+				log.Printf("Error in onRelease callback: %v", err)
+			}
+		}
+	}
+}
+
+func (s *PinService) Stop() error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()    // Signal goroutine to stop
+		s.wg.Wait() // Wait for goroutine to finish
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onPush = nil
+	s.onRelease = nil
+	s.onGesture = nil
+	return nil
+}
+
+func (s *PinService) GetCurrentState() (ButtonState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.debouncer.State(), nil
+}
+
+func (s *PinService) OnPush(fn func(state ButtonState, at time.Time) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onPush = append(s.onPush, fn)
+}
+
+func (s *PinService) OnRelease(fn func(state ButtonState, at time.Time) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onRelease = append(s.onRelease, fn)
+}
+
+func (s *PinService) OnGesture(fn func(gesture Gesture, at time.Time) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.onGesture = append(s.onGesture, fn)
+}
@@ -0,0 +1,32 @@
+package buttons
+
+import (
+	"fmt"
+	"time"
+)
+
+// SessionRules are the limits of a valid ventilation session, a zero value disables the limit
+type SessionRules struct {
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	// MinGap is the minimum time between the end of the previous session and the start of the next
+	MinGap time.Duration
+}
+
+// Validate returns why a session is rejected or an empty string if it is valid,
+// previousEnd is the end of the previous accepted session of the button or zero without one
+func (r SessionRules) Validate(startAt time.Time, endAt time.Time, previousEnd time.Time) string {
+	duration := endAt.Sub(startAt)
+	switch {
+	case duration < 0:
+		return "ends before it starts"
+	case r.MinDuration > 0 && duration < r.MinDuration:
+		return fmt.Sprintf("shorter than %v (%v)", r.MinDuration, duration.Round(time.Second))
+	case r.MaxDuration > 0 && duration > r.MaxDuration:
+		return fmt.Sprintf("longer than %v (%v)", r.MaxDuration, duration.Round(time.Second))
+	case r.MinGap > 0 && !previousEnd.IsZero() && startAt.Sub(previousEnd) < r.MinGap:
+		return fmt.Sprintf("starts %v after the previous session, less than %v",
+			startAt.Sub(previousEnd).Round(time.Second), r.MinGap)
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package buttons
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRulesValidate(t *testing.T) {
+	rules := SessionRules{MinDuration: 5 * time.Second, MaxDuration: 3 * time.Hour, MinGap: 10 * time.Second}
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name        string
+		rules       SessionRules
+		duration    time.Duration
+		previousEnd time.Time
+		// empty for a valid session, otherwise a part of the reason
+		want string
+	}{
+		{name: "valid", rules: rules, duration: 10 * time.Minute},
+		{name: "valid after a gap", rules: rules, duration: 10 * time.Minute, previousEnd: start.Add(-time.Minute)},
+		{name: "exactly the minimum", rules: rules, duration: 5 * time.Second},
+		{name: "exactly the maximum", rules: rules, duration: 3 * time.Hour},
+		{name: "exactly the minimum gap", rules: rules, duration: time.Minute, previousEnd: start.Add(-10 * time.Second)},
+		{name: "ends before it starts", rules: rules, duration: -time.Second, want: "ends before it starts"},
+		{name: "too short", rules: rules, duration: 4 * time.Second, want: "shorter than 5s"},
+		{name: "too long", rules: rules, duration: 3*time.Hour + time.Second, want: "longer than 3h0m0s"},
+		{
+			name:        "too close to the previous session",
+			rules:       rules,
+			duration:    time.Minute,
+			previousEnd: start.Add(-9 * time.Second),
+			want:        "starts 9s after the previous session",
+		},
+		{name: "disabled rules", duration: 24 * time.Hour, previousEnd: start},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.rules.Validate(start, start.Add(tt.duration), tt.previousEnd)
+			if tt.want == "" && reason != "" {
+				t.Errorf("Validate() rejected a valid session: %s", reason)
+			}
+			if tt.want != "" && !strings.Contains(reason, tt.want) {
+				t.Errorf("Validate() = %q, want a reason containing %q", reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewButtonAppSessionLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ButtonAppOption
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "default timeout within the maximum", options: []ButtonAppOption{
+			WithSessionRules(SessionRules{MaxDuration: 3 * time.Hour}),
+		}},
+		{name: "timeout within the maximum", options: []ButtonAppOption{
+			WithSessionTimeout(time.Hour),
+			WithSessionRules(SessionRules{MaxDuration: 2 * time.Hour}),
+		}},
+		{name: "timeout without a maximum", options: []ButtonAppOption{
+			WithSessionTimeout(24 * time.Hour),
+			WithSessionRules(SessionRules{MinDuration: time.Second}),
+		}},
+		{name: "timeout longer than the maximum", options: []ButtonAppOption{
+			WithSessionTimeout(4 * time.Hour),
+			WithSessionRules(SessionRules{MaxDuration: 3 * time.Hour}),
+		}, wantErr: true},
+		{name: "options in the other order", options: []ButtonAppOption{
+			WithSessionRules(SessionRules{MaxDuration: 3 * time.Hour}),
+			WithSessionTimeout(4 * time.Hour),
+		}, wantErr: true},
+		{name: "maximum shorter than the minimum", options: []ButtonAppOption{
+			WithSessionRules(SessionRules{MinDuration: time.Hour, MaxDuration: time.Minute}),
+		}, wantErr: true},
+		{name: "negative rule", options: []ButtonAppOption{
+			WithSessionRules(SessionRules{MinGap: -time.Second}),
+		}, wantErr: true},
+		{name: "zero timeout", options: []ButtonAppOption{WithSessionTimeout(0)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewButtonApp(1, nil, nil, tt.options...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewButtonApp() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
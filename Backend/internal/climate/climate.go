@@ -0,0 +1,135 @@
+// Package climate derives comfort metrics from temperature (°C) and relative humidity (%)
+package climate
+
+import "math"
+
+// Magnus formula coefficients over water, valid from -45°C to 60°C
+const (
+	magnusA = 17.62
+	magnusB = 243.12
+)
+
+// SaturationVaporPressure returns the saturation vapor pressure in hPa
+func SaturationVaporPressure(temperature float64) float64 {
+	return 6.112 * math.Exp(magnusA*temperature/(magnusB+temperature))
+}
+
+// DewPoint returns the temperature in °C at which the air becomes saturated
+func DewPoint(temperature, humidity float64) float64 {
+	// the logarithm is undefined for completely dry air
+	humidity = math.Max(humidity, 0.1)
+	gamma := math.Log(humidity/100) + magnusA*temperature/(magnusB+temperature)
+	return magnusB * gamma / (magnusA - gamma)
+}
+
+// AbsoluteHumidity returns the water content of the air in g/m³
+func AbsoluteHumidity(temperature, humidity float64) float64 {
+	vaporPressure := SaturationVaporPressure(temperature) * humidity / 100
+	// 216.7 = molar mass of water / universal gas constant, converted to g/m³ with hPa
+	return 216.7 * vaporPressure / (273.15 + temperature)
+}
+
+// RelativeHumidity returns the relative humidity in % of air with the given absolute humidity at a temperature
+func RelativeHumidity(temperature, absoluteHumidity float64) float64 {
+	vaporPressure := absoluteHumidity * (273.15 + temperature) / 216.7
+	return vaporPressure / SaturationVaporPressure(temperature) * 100
+}
+
+// HeatIndex returns the apparent temperature in °C using the NOAA formula (Rothfusz regression).
+// Below about 26.7°C (80°F) humidity hardly affects the apparent temperature, so the air temperature is returned.
+func HeatIndex(temperature, humidity float64) float64 {
+	t := temperature*9/5 + 32
+
+	simple := 0.5 * (t + 61.0 + (t-68.0)*1.2 + humidity*0.094)
+	if (simple+t)/2 < 80 {
+		return temperature
+	}
+
+	hi := -42.379 + 2.04901523*t + 10.14333127*humidity -
+		0.22475541*t*humidity - 0.00683783*t*t -
+		0.05481717*humidity*humidity + 0.00122874*t*t*humidity +
+		0.00085282*t*humidity*humidity - 0.00000199*t*t*humidity*humidity
+
+	if humidity < 13 && t >= 80 && t <= 112 {
+		hi -= (13 - humidity) / 4 * math.Sqrt((17-math.Abs(t-95))/17)
+	} else if humidity > 85 && t >= 80 && t <= 87 {
+		hi += (humidity - 85) / 10 * (87 - t) / 5
+	}
+
+	return (hi - 32) * 5 / 9
+}
+
+// CriticalHumidity returns the relative humidity in % above which mold can grow at the given temperature.
+// It uses the lowest isopleth of the VTT mold growth model (Hukka & Viitanen), below 0°C no growth is possible.
+func CriticalHumidity(temperature float64) float64 {
+	if temperature <= 0 {
+		return 100
+	}
+	if temperature > 20 {
+		return 80
+	}
+	t := temperature
+	return -0.00267*t*t*t + 0.160*t*t - 3.13*t + 100
+}
+
+// Round rounds to the given number of decimals
+func Round(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
+
+// Metrics are the averaged temperature, humidity and derived metrics of several readings
+type Metrics struct {
+	Temperature      float64
+	Humidity         float64
+	DewPoint         float64
+	AbsoluteHumidity float64
+	HeatIndex        float64
+}
+
+// Map returns the metrics keyed like the JSON fields of a reading
+func (m Metrics) Map() map[string]float64 {
+	return map[string]float64{
+		"temperature":       m.Temperature,
+		"humidity":          m.Humidity,
+		"dew_point":         m.DewPoint,
+		"absolute_humidity": m.AbsoluteHumidity,
+		"heat_index":        m.HeatIndex,
+	}
+}
+
+// Average averages the metrics derived from every single reading. They differ from the metrics of the averaged
+// temperature and humidity, as the formulas are not linear.
+type Average struct {
+	count            int
+	temperature      float64
+	humidity         float64
+	dewPoint         float64
+	absoluteHumidity float64
+	heatIndex        float64
+}
+
+// Add adds a reading
+func (a *Average) Add(temperature, humidity float64) {
+	a.count++
+	a.temperature += temperature
+	a.humidity += humidity
+	a.dewPoint += DewPoint(temperature, humidity)
+	a.absoluteHumidity += AbsoluteHumidity(temperature, humidity)
+	a.heatIndex += HeatIndex(temperature, humidity)
+}
+
+// Metrics returns the rounded averages, all zero without readings
+func (a *Average) Metrics() Metrics {
+	if a.count == 0 {
+		return Metrics{}
+	}
+	n := float64(a.count)
+	return Metrics{
+		Temperature:      Round(a.temperature/n, 1),
+		Humidity:         Round(a.humidity/n, 1),
+		DewPoint:         Round(a.dewPoint/n, 1),
+		AbsoluteHumidity: Round(a.absoluteHumidity/n, 2),
+		HeatIndex:        Round(a.heatIndex/n, 1),
+	}
+}
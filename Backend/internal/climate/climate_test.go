@@ -0,0 +1,141 @@
+package climate
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name             string
+		temperature      float64
+		humidity         float64
+		dewPoint         float64
+		absoluteHumidity float64
+		heatIndex        float64
+	}{
+		{name: "room", temperature: 20, humidity: 50, dewPoint: 9.3, absoluteHumidity: 8.6, heatIndex: 20},
+		{name: "humid room", temperature: 25, humidity: 60, dewPoint: 16.7, absoluteHumidity: 13.8, heatIndex: 25},
+		{name: "saturated", temperature: 10, humidity: 100, dewPoint: 10, absoluteHumidity: 9.4, heatIndex: 10},
+		{name: "freezing", temperature: 0, humidity: 100, dewPoint: 0, absoluteHumidity: 4.8, heatIndex: 0},
+		{name: "frost", temperature: -10, humidity: 80, dewPoint: -12.8, absoluteHumidity: 1.9, heatIndex: -10},
+		// NOAA heat index chart: 90°F at 70% feels like 106°F, 95°F at 50% like 105°F
+		{name: "hot and humid", temperature: 32.2, humidity: 70, dewPoint: 26.0, absoluteHumidity: 23.8,
+			heatIndex: 41.1},
+		{name: "hot", temperature: 35, humidity: 50, dewPoint: 23.0, absoluteHumidity: 19.8, heatIndex: 40.6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DewPoint(tt.temperature, tt.humidity); math.Abs(got-tt.dewPoint) > 0.1 {
+				t.Errorf("DewPoint() = %.2f°C, want %.1f°C", got, tt.dewPoint)
+			}
+			if got := AbsoluteHumidity(tt.temperature, tt.humidity); math.Abs(got-tt.absoluteHumidity) > 0.1 {
+				t.Errorf("AbsoluteHumidity() = %.2f g/m³, want %.1f g/m³", got, tt.absoluteHumidity)
+			}
+			if got := HeatIndex(tt.temperature, tt.humidity); math.Abs(got-tt.heatIndex) > 0.5 {
+				t.Errorf("HeatIndex() = %.2f°C, want %.1f°C", got, tt.heatIndex)
+			}
+		})
+	}
+}
+
+func TestDewPointOfDryAir(t *testing.T) {
+	for _, temperature := range []float64{-20, 0, 30} {
+		dewPoint := DewPoint(temperature, 0)
+		if math.IsInf(dewPoint, 0) || math.IsNaN(dewPoint) || dewPoint >= temperature {
+			t.Errorf("DewPoint(%.0f, 0) = %f, want a finite dew point below the temperature", temperature, dewPoint)
+		}
+	}
+}
+
+func TestRelativeHumidity(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		humidity    float64
+	}{
+		{temperature: -10, humidity: 80},
+		{temperature: 5, humidity: 95},
+		{temperature: 21, humidity: 55},
+		{temperature: 35, humidity: 20},
+	}
+
+	for _, tt := range tests {
+		absolute := AbsoluteHumidity(tt.temperature, tt.humidity)
+		if got := RelativeHumidity(tt.temperature, absolute); math.Abs(got-tt.humidity) > 1e-9 {
+			t.Errorf("RelativeHumidity(%.0f, %.2f) = %f%%, want %.0f%%", tt.temperature, absolute, got, tt.humidity)
+		}
+	}
+
+	// outdoor air of 0°C at 80% warmed up to 20°C
+	if got := RelativeHumidity(20, AbsoluteHumidity(0, 80)); math.Abs(got-22.5) > 0.5 {
+		t.Errorf("warmed up air has %.1f%%, want about 22.5%%", got)
+	}
+}
+
+func TestAverage(t *testing.T) {
+	var empty Average
+	if metrics := empty.Metrics(); metrics != (Metrics{}) {
+		t.Errorf("Metrics() without readings = %+v, want zero", metrics)
+	}
+
+	var average Average
+	average.Add(10, 90)
+	average.Add(30, 30)
+	metrics := average.Metrics()
+
+	if metrics.Temperature != 20 || metrics.Humidity != 60 {
+		t.Errorf("averaged %.1f°C and %.1f%%, want 20°C and 60%%", metrics.Temperature, metrics.Humidity)
+	}
+	wantDewPoint := Round((DewPoint(10, 90)+DewPoint(30, 30))/2, 1)
+	if metrics.DewPoint != wantDewPoint {
+		t.Errorf("dew point %.1f°C, want the average %.1f°C of the single readings", metrics.DewPoint, wantDewPoint)
+	}
+	if metrics.DewPoint == Round(DewPoint(20, 60), 1) {
+		t.Error("dew point derived from the averaged temperature and humidity")
+	}
+	wantAbsoluteHumidity := Round((AbsoluteHumidity(10, 90)+AbsoluteHumidity(30, 30))/2, 2)
+	if metrics.AbsoluteHumidity != wantAbsoluteHumidity {
+		t.Errorf("absolute humidity %.2f g/m³, want %.2f g/m³", metrics.AbsoluteHumidity, wantAbsoluteHumidity)
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value    float64
+		decimals int
+		want     float64
+	}{
+		{value: 8.6214, decimals: 2, want: 8.62},
+		{value: 9.25, decimals: 1, want: 9.3},
+		{value: -12.79, decimals: 1, want: -12.8},
+		{value: 21.5, decimals: 0, want: 22},
+	}
+
+	for _, tt := range tests {
+		if got := Round(tt.value, tt.decimals); got != tt.want {
+			t.Errorf("Round(%v, %d) = %v, want %v", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestCriticalHumidity(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		want        float64
+	}{
+		{temperature: -5, want: 100},
+		{temperature: 0, want: 100},
+		{temperature: 5, want: 88.0},
+		{temperature: 10, want: 82.0},
+		{temperature: 15, want: 80.0},
+		{temperature: 20, want: 80.0},
+		{temperature: 30, want: 80},
+	}
+
+	for _, tt := range tests {
+		if got := CriticalHumidity(tt.temperature); math.Abs(got-tt.want) > 0.1 {
+			t.Errorf("CriticalHumidity(%.0f) = %.2f%%, want %.1f%%", tt.temperature, got, tt.want)
+		}
+	}
+}
@@ -3,18 +3,37 @@ package contracts
 import "time"
 
 type SensorReading struct {
-	ID          int64     `json:"id"`
-	SensorID    int       `json:"sensor_id"`
-	Temperature float64   `json:"temperature"`
-	Humidity    float64   `json:"humidity"`
-	Timestamp   time.Time `json:"timestamp"`
+	ID             int64     `json:"id"`
+	SensorID       int       `json:"sensor_id"`
+	Temperature    float64   `json:"temperature"`
+	Humidity       float64   `json:"humidity"`
+	RawTemperature float64   `json:"raw_temperature"`
+	RawHumidity    float64   `json:"raw_humidity"`
+	Timestamp      time.Time `json:"timestamp"`
+
+	// derived from temperature and humidity, not stored
+	DewPoint         float64 `json:"dew_point"`
+	AbsoluteHumidity float64 `json:"absolute_humidity"`
+	HeatIndex        float64 `json:"heat_index"`
 }
 
+// sources of a ButtonReading
+const (
+	SourceGPIO   = "gpio"
+	SourceManual = "manual"
+)
+
+// ButtonReading is a ventilation session, EndAt is nil while the session is in progress.
+// Source tells whether it was recorded by the button or entered manually.
+// Rejected sessions violated the session rules, they are kept for review but not analyzed.
 type ButtonReading struct {
-	ID       int64     `json:"id"`
-	ButtonID int       `json:"button_id"`
-	StartAt  time.Time `json:"start_at"`
-	EndAt    time.Time `json:"end_at"`
+	ID           int64      `json:"id"`
+	ButtonID     int        `json:"button_id"`
+	StartAt      time.Time  `json:"start_at"`
+	EndAt        *time.Time `json:"end_at"`
+	Source       string     `json:"source"`
+	Rejected     bool       `json:"rejected"`
+	RejectReason string     `json:"reject_reason,omitempty"`
 }
 
 type WeatherData struct {
@@ -26,4 +45,202 @@ type WeatherData struct {
 	Temperature float32   `json:"temperature"`
 	Humidity    float32   `json:"humidity"`
 	FeelsLike   float32   `json:"feels_like"`
+
+	// derived from temperature and humidity, not stored
+	DewPoint         float32 `json:"dew_point"`
+	AbsoluteHumidity float32 `json:"absolute_humidity"`
+	HeatIndex        float32 `json:"heat_index"`
+}
+
+// WeatherAggregate summarizes the weather data of a location in the hour or day starting at Time
+type WeatherAggregate struct {
+	Name           string    `json:"name"`
+	Time           time.Time `json:"time"`
+	Count          int       `json:"count"`
+	TemperatureMin float32   `json:"temperature_min"`
+	TemperatureAvg float32   `json:"temperature_avg"`
+	TemperatureMax float32   `json:"temperature_max"`
+	HumidityMin    float32   `json:"humidity_min"`
+	HumidityAvg    float32   `json:"humidity_avg"`
+	HumidityMax    float32   `json:"humidity_max"`
+
+	// averaged over the values derived from the single readings, not stored
+	AbsoluteHumidity float32 `json:"absolute_humidity"`
+}
+
+// resolutions of a WeatherForecast and a WeatherAggregate
+const (
+	ResolutionHourly = "hourly"
+	ResolutionDaily  = "daily"
+)
+
+// WeatherForecast is the forecast for the hour or day starting at Time, fetched at FetchedAt.
+// Hourly forecasts have the same minimum, maximum and temperature.
+type WeatherForecast struct {
+	ID                       int64     `json:"id"`
+	Name                     string    `json:"name"`
+	Resolution               string    `json:"resolution"`
+	FetchedAt                time.Time `json:"fetched_at"`
+	Time                     time.Time `json:"time"`
+	Temperature              float32   `json:"temperature"`
+	TemperatureMin           float32   `json:"temperature_min"`
+	TemperatureMax           float32   `json:"temperature_max"`
+	Humidity                 float32   `json:"humidity"`
+	PrecipitationProbability float32   `json:"precipitation_probability"`
+	DewPoint                 float32   `json:"dew_point"`
+	AbsoluteHumidity         float32   `json:"absolute_humidity"`
+}
+
+// WeatherAlert is an official weather warning for a location, valid from StartAt to EndAt.
+// The severity is one of the alert severities info, warning and error.
+type WeatherAlert struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Sender      string    `json:"sender"`
+	Event       string    `json:"event"`
+	Severity    string    `json:"severity"`
+	StartAt     time.Time `json:"start_at"`
+	EndAt       time.Time `json:"end_at"`
+	Description string    `json:"description"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Button is a push button or window contact on a GPIO pin, optionally associated with the sensor of its room
+type Button struct {
+	ID       int    `json:"id"`
+	Pin      int    `json:"pin"`
+	Name     string `json:"name"`
+	Room     string `json:"room"`
+	SensorID int    `json:"sensor_id"`
+	// Mode is contact (ventilation while open) or push (gestures mapped to actions)
+	Mode string `json:"mode"`
+}
+
+// ButtonEvent is a recognized gesture of a push button and the action it triggered
+type ButtonEvent struct {
+	ID       int64     `json:"id"`
+	ButtonID int       `json:"button_id"`
+	Gesture  string    `json:"gesture"`
+	Action   string    `json:"action"`
+	Label    string    `json:"label"`
+	At       time.Time `json:"at"`
+}
+
+type Sensor struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Room     string `json:"room"`
+	RedisKey string `json:"redis_key"`
+	Source   string `json:"source"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type SensorCalibration struct {
+	ID                int64     `json:"id"`
+	SensorID          int       `json:"sensor_id"`
+	TemperatureOffset float64   `json:"temperature_offset"`
+	TemperatureGain   float64   `json:"temperature_gain"`
+	HumidityOffset    float64   `json:"humidity_offset"`
+	HumidityGain      float64   `json:"humidity_gain"`
+	ValidFrom         time.Time `json:"valid_from"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type QuarantinedReading struct {
+	ID             int64     `json:"id"`
+	SensorID       int       `json:"sensor_id"`
+	Temperature    float64   `json:"temperature"`
+	Humidity       float64   `json:"humidity"`
+	RawTemperature float64   `json:"raw_temperature"`
+	RawHumidity    float64   `json:"raw_humidity"`
+	Timestamp      time.Time `json:"timestamp"`
+	Filter         string    `json:"filter"`
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type SensorStatus struct {
+	SensorID            int       `json:"sensor_id"`
+	TotalReads          int       `json:"total_reads"`
+	TotalFailures       int       `json:"total_failures"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastError           string    `json:"last_error"`
+	LastErrorAt         time.Time `json:"last_error_at"`
+	LastSuccessAt       time.Time `json:"last_success_at"`
+	LastFreshAt         time.Time `json:"last_fresh_at"`
+	Stale               bool      `json:"stale"`
+}
+
+type Alert struct {
+	Key      string    `json:"key"`
+	Source   string    `json:"source"`
+	Severity string    `json:"severity"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	RaisedAt time.Time `json:"raised_at"`
+}
+
+type IngestResult struct {
+	Stored      int      `json:"stored"`
+	Duplicates  int      `json:"duplicates"`
+	Quarantined int      `json:"quarantined"`
+	Errors      []string `json:"errors"`
+}
+
+// MoldRisk summarizes the hours a sensor spent above the critical humidity for mold growth
+type MoldRisk struct {
+	SensorID int `json:"sensor_id"`
+	Days     int `json:"days"`
+	// Hours is the number of hours with readings in the period
+	Hours                 int     `json:"hours"`
+	CriticalHours         int     `json:"critical_hours"`
+	LongestCriticalStreak int     `json:"longest_critical_streak"`
+	Score                 float64 `json:"score"`
+	Level                 string  `json:"level"`
+}
+
+// VentilationRecommendation tells whether airing a room lowers its humidity, with the reasoning behind it
+type VentilationRecommendation struct {
+	SensorID int    `json:"sensor_id"`
+	Action   string `json:"action"`
+	// Minutes is the suggested duration of the ventilation, 0 unless the action is to ventilate
+	Minutes                 int       `json:"minutes"`
+	Urgent                  bool      `json:"urgent"`
+	IndoorTemperature       float64   `json:"indoor_temperature"`
+	IndoorHumidity          float64   `json:"indoor_humidity"`
+	IndoorAbsoluteHumidity  float64   `json:"indoor_absolute_humidity"`
+	OutdoorTemperature      float64   `json:"outdoor_temperature"`
+	OutdoorHumidity         float64   `json:"outdoor_humidity"`
+	OutdoorAbsoluteHumidity float64   `json:"outdoor_absolute_humidity"`
+	ExpectedHumidity        float64   `json:"expected_humidity"`
+	Reasons                 []string  `json:"reasons"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// VentilationMetrics are the conditions of a room in one phase of a ventilation
+type VentilationMetrics struct {
+	Temperature      float64 `json:"temperature"`
+	Humidity         float64 `json:"humidity"`
+	AbsoluteHumidity float64 `json:"absolute_humidity"`
+}
+
+// VentilationAnalysis measures the effect of a ventilation (button event) on the room of a sensor
+type VentilationAnalysis struct {
+	ID              int64     `json:"id"`
+	ButtonReadingID int64     `json:"button_reading_id"`
+	ButtonID        int       `json:"button_id"`
+	SensorID        int       `json:"sensor_id"`
+	StartAt         time.Time `json:"start_at"`
+	EndAt           time.Time `json:"end_at"`
+	// Before is the average before the window was opened, During the lowest values while it was open
+	// and After the average once the room settled, nil without readings after the ventilation
+	Before               VentilationMetrics  `json:"before"`
+	During               VentilationMetrics  `json:"during"`
+	After                *VentilationMetrics `json:"after"`
+	AbsoluteHumidityDrop float64             `json:"absolute_humidity_drop"`
+	TemperatureLoss      float64             `json:"temperature_loss"`
+	// RecoveryMinutes is the time after closing the window until the temperature was back
+	Recovered       bool      `json:"recovered"`
+	RecoveryMinutes float64   `json:"recovery_minutes"`
+	CreatedAt       time.Time `json:"created_at"`
 }
@@ -32,11 +32,32 @@ func WithAfterCleanUp(fn func() error) AppOption {
 	}
 }
 
+// WithMinAge keeps the readings of ventilations that ended less than age ago, e.g. until they are analyzed.
+// They are cleaned up in a later run.
+func WithMinAge(age time.Duration) AppOption {
+	return func(app *App) error {
+		app.minAge = age
+		return nil
+	}
+}
+
+// WithButtonSensors only deletes the readings of the sensor in the room of a button (button ID to sensor ID),
+// for buttons without an entry the readings of all sensors are deleted
+func WithButtonSensors(sensors map[int]int) AppOption {
+	return func(app *App) error {
+		app.buttonSensors = sensors
+		return nil
+	}
+}
+
 type App struct {
 	btnRepo    ButtonRepository
 	sensorRepo SensorRepository
 	start      bool
 	stop       chan bool
+	minAge     time.Duration
+
+	buttonSensors map[int]int
 
 	beforeCleanUp func() error
 	afterCleanUp  func() error
@@ -154,6 +175,10 @@ func (a *App) dataCleanUp() error {
 }
 
 func (a *App) cleanUpForSensor(reading *contracts.ButtonReading) (int, error) {
+	if reading.EndAt == nil || time.Since(*reading.EndAt) < a.minAge {
+		return 0, nil
+	}
+
 	// get all sensor readings in between [buttonStart, buttonEnd+10min] and delete them
 	sensorEndTime := reading.EndAt.Add(time.Minute * 10)
 
@@ -162,8 +187,12 @@ func (a *App) cleanUpForSensor(reading *contracts.ButtonReading) (int, error) {
 		return 0, err
 	}
 
+	roomSensor, hasRoom := a.buttonSensors[reading.ButtonID]
 	counter := 0
 	for _, sensor := range allSensorData {
+		if hasRoom && sensor.SensorID != roomSensor {
+			continue
+		}
 		if err := a.sensorRepo.Delete(sensor.ID); err != nil {
 			return counter, err
 		}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+type ButtonEventRepository interface {
+	GetEvents(offset int, limit int) ([]*contracts.ButtonEvent, error)
+}
+
+// WithButtonEvents shows the recognized button gestures and enables GET /api/button-events
+func WithButtonEvents(repo ButtonEventRepository) Option {
+	return func(h *Handler) error {
+		h.eventRepo = repo
+		return nil
+	}
+}
+
+func (h *Handler) getButtonEvents(limit int) ([]*contracts.ButtonEvent, error) {
+	if h.eventRepo == nil {
+		return nil, nil
+	}
+	return h.eventRepo.GetEvents(0, limit)
+}
+
+// ServeButtonEvents returns the button gestures and their actions, paginated by ?offset= and ?limit= (default 50)
+func (h *Handler) ServeButtonEvents(w http.ResponseWriter, r *http.Request) {
+	if h.eventRepo == nil {
+		http.Error(w, "Button events not configured", http.StatusNotFound)
+		return
+	}
+
+	offset, limit := 0, 50
+	if value := r.URL.Query().Get("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.eventRepo.GetEvents(offset, limit)
+	if err != nil {
+		log.Printf("Error getting button events: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Calibrator interface {
+	History(sensorID int) ([]*contracts.SensorCalibration, error)
+	Add(cal contracts.SensorCalibration, recompute bool) (*contracts.SensorCalibration, int64, error)
+}
+
+// WithCalibrator enables the calibration endpoints
+func WithCalibrator(calibrator Calibrator) Option {
+	return func(h *Handler) error {
+		h.calibrator = calibrator
+		return nil
+	}
+}
+
+type calibrationRequest struct {
+	SensorID          int        `json:"sensor_id"`
+	TemperatureOffset float64    `json:"temperature_offset"`
+	TemperatureGain   *float64   `json:"temperature_gain"`
+	HumidityOffset    float64    `json:"humidity_offset"`
+	HumidityGain      *float64   `json:"humidity_gain"`
+	ValidFrom         *time.Time `json:"valid_from"`
+	Recompute         bool       `json:"recompute"`
+}
+
+type calibrationResponse struct {
+	Calibration *contracts.SensorCalibration `json:"calibration"`
+	Recomputed  int64                        `json:"recomputed"`
+}
+
+// ServeCalibrations returns the calibration history, optionally filtered by ?sensor_id=
+func (h *Handler) ServeCalibrations(w http.ResponseWriter, r *http.Request) {
+	if h.calibrator == nil {
+		http.Error(w, "Calibration not configured", http.StatusNotFound)
+		return
+	}
+
+	sensorID := 0
+	if value := r.URL.Query().Get("sensor_id"); value != "" {
+		var err error
+		sensorID, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, "Invalid sensor_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := h.calibrator.History(sensorID)
+	if err != nil {
+		log.Printf("Error getting calibrations: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(history)
+}
+
+// CreateCalibration stores a new calibration for a sensor, gains default to 1
+func (h *Handler) CreateCalibration(w http.ResponseWriter, r *http.Request) {
+	if h.calibrator == nil {
+		http.Error(w, "Calibration not configured", http.StatusNotFound)
+		return
+	}
+
+	var req calibrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.SensorID <= 0 {
+		http.Error(w, "sensor_id is required", http.StatusBadRequest)
+		return
+	}
+
+	cal := contracts.SensorCalibration{
+		SensorID:          req.SensorID,
+		TemperatureOffset: req.TemperatureOffset,
+		TemperatureGain:   1,
+		HumidityOffset:    req.HumidityOffset,
+		HumidityGain:      1,
+	}
+	if req.TemperatureGain != nil {
+		cal.TemperatureGain = *req.TemperatureGain
+	}
+	if req.HumidityGain != nil {
+		cal.HumidityGain = *req.HumidityGain
+	}
+	if req.ValidFrom != nil {
+		cal.ValidFrom = *req.ValidFrom
+	}
+	if cal.TemperatureGain == 0 || cal.HumidityGain == 0 {
+		http.Error(w, "Gain must not be 0", http.StatusBadRequest)
+		return
+	}
+
+	stored, recomputed, err := h.calibrator.Add(cal, req.Recompute)
+	if err != nil {
+		log.Printf("Error adding calibration: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(calibrationResponse{
+		Calibration: stored,
+		Recomputed:  recomputed,
+	})
+}
@@ -3,10 +3,13 @@ package handler
 import (
 	"BeRoHuTe/internal/contracts"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 type SensorRepository interface {
@@ -15,6 +18,8 @@ type SensorRepository interface {
 	GetAverageLastHour() (map[int]map[string]float64, error)
 	GetAverageToday() (map[int]map[string]float64, error)
 	GetAverageThisWeek() (map[int]map[string]float64, error)
+	GetQuarantined(n int) ([]*contracts.QuarantinedReading, error)
+	GetMoldRisk(days int) ([]*contracts.MoldRisk, error)
 }
 
 type ButtonRepository interface {
@@ -23,6 +28,57 @@ type ButtonRepository interface {
 
 type WeatherRepository interface {
 	GetLatest() ([]*contracts.WeatherData, error)
+	GetInBetween(name string, start time.Time, end time.Time) ([]*contracts.WeatherData, error)
+	GetAggregates(name string, resolution string, start time.Time, end time.Time) ([]*contracts.WeatherAggregate, error)
+	GetAverageLastHour() (map[string]map[string]float64, error)
+	GetAverageToday() (map[string]map[string]float64, error)
+	GetAverageThisWeek() (map[string]map[string]float64, error)
+}
+
+type SensorRegistry interface {
+	GetAll() ([]*contracts.Sensor, error)
+}
+
+type SensorStatusProvider interface {
+	Status() []contracts.SensorStatus
+}
+
+type AlertProvider interface {
+	Active() []contracts.Alert
+}
+
+type Option func(*Handler) error
+
+// WithAlerts shows the active alerts on the dashboard
+func WithAlerts(provider AlertProvider) Option {
+	return func(h *Handler) error {
+		h.alerts = provider
+		return nil
+	}
+}
+
+// WithSensorRegistry shows the configured sensor names instead of the plain IDs
+func WithSensorRegistry(registry SensorRegistry) Option {
+	return func(h *Handler) error {
+		h.registry = registry
+		return nil
+	}
+}
+
+// WithButtons shows the configured button names instead of the plain IDs
+func WithButtons(buttons []*contracts.Button) Option {
+	return func(h *Handler) error {
+		h.buttons = buttons
+		return nil
+	}
+}
+
+// WithSensorStatus shows the per-sensor read failures
+func WithSensorStatus(provider SensorStatusProvider) Option {
+	return func(h *Handler) error {
+		h.statusProvider = provider
+		return nil
+	}
 }
 
 type Handler struct {
@@ -30,6 +86,26 @@ type Handler struct {
 	btnRepo     ButtonRepository
 	indexTpl    *template.Template
 	weatherRepo WeatherRepository
+	registry    SensorRegistry
+	buttons     []*contracts.Button
+	calibrator  Calibrator
+	recommender VentilationRecommender
+
+	analysisRepo      VentilationAnalysisRepository
+	eventRepo         ButtonEventRepository
+	ventilationEvents VentilationEventRepository
+	forecastRepo      ForecastRepository
+	forecastLocation  string
+	weatherAlertRepo  WeatherAlertRepository
+
+	statusProvider SensorStatusProvider
+	alerts         AlertProvider
+
+	ingester    ReadingIngester
+	pushTokens  map[string]string
+	pushSensors map[int]string
+
+	units Units
 }
 
 type DashboardData struct {
@@ -40,21 +116,114 @@ type DashboardData struct {
 	Last100           []*contracts.SensorReading
 	LastButtonPushes  []*contracts.ButtonReading
 	LatestWeatherData []*contracts.WeatherData
+	Sensors           []*contracts.Sensor
+	Buttons           []*contracts.Button
+	SensorStatus      []contracts.SensorStatus
+	Alerts            []contracts.Alert
+	MoldRiskWeek      []*contracts.MoldRisk
+	MoldRiskMonth     []*contracts.MoldRisk
+	Ventilation       []*contracts.VentilationRecommendation
+	VentilationLog    []*contracts.VentilationAnalysis
+	ButtonEvents      []*contracts.ButtonEvent
+	Forecast          []*contracts.WeatherForecast
+	WeatherAlerts     []*contracts.WeatherAlert
+	// Units are the units the temperatures are presented in, the values are in °C
+	Units Units
+}
+
+// SensorName returns the configured name of a sensor, falling back to its ID
+func (d DashboardData) SensorName(sensorID int) string {
+	for _, sensor := range d.Sensors {
+		if sensor.ID == sensorID {
+			return sensor.Name
+		}
+	}
+	return fmt.Sprintf("Sensor %d", sensorID)
+}
+
+// ButtonName returns the configured name of a button, falling back to its ID
+func (d DashboardData) ButtonName(buttonID int) string {
+	for _, button := range d.Buttons {
+		if button.ID == buttonID {
+			return button.Name
+		}
+	}
+	return fmt.Sprintf("Window %d", buttonID)
+}
+
+// StatusOf returns the read statistics of a sensor or nil if it was not read yet
+func (d DashboardData) StatusOf(sensorID int) *contracts.SensorStatus {
+	for i := range d.SensorStatus {
+		if d.SensorStatus[i].SensorID == sensorID {
+			return &d.SensorStatus[i]
+		}
+	}
+	return nil
+}
+
+// MonthlyMoldRisk returns the mold risk of a sensor over the last 30 days or nil without data
+func (d DashboardData) MonthlyMoldRisk(sensorID int) *contracts.MoldRisk {
+	for _, risk := range d.MoldRiskMonth {
+		if risk.SensorID == sensorID {
+			return risk
+		}
+	}
+	return nil
+}
+
+// DriestHour returns the forecast hour with the lowest absolute humidity, the best hour to ventilate
+func (d DashboardData) DriestHour() *contracts.WeatherForecast {
+	var driest *contracts.WeatherForecast
+	for _, forecast := range d.Forecast {
+		if driest == nil || forecast.AbsoluteHumidity < driest.AbsoluteHumidity {
+			driest = forecast
+		}
+	}
+	return driest
 }
 
 func New(repo SensorRepository, templateDir string, btnRepo ButtonRepository,
-	weatherRepo WeatherRepository) (*Handler, error) {
+	weatherRepo WeatherRepository, options ...Option) (*Handler, error) {
 	tpl, err := template.ParseFiles(filepath.Join(templateDir, "index.html"))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Handler{
+	h := &Handler{
 		repo:        repo,
 		indexTpl:    tpl,
 		btnRepo:     btnRepo,
 		weatherRepo: weatherRepo,
-	}, nil
+	}
+
+	for _, option := range options {
+		if err := option(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+func (h *Handler) getSensors() ([]*contracts.Sensor, error) {
+	if h.registry == nil {
+		return nil, nil
+	}
+	return h.registry.GetAll()
+}
+
+func (h *Handler) getAlerts() []contracts.Alert {
+	if h.alerts == nil {
+		return nil
+	}
+	return h.alerts.Active()
+}
+
+func (h *Handler) getSensorStatus() []contracts.SensorStatus {
+	if h.statusProvider == nil {
+		return nil
+	}
+	return h.statusProvider.Status()
 }
 
 // ServeIndex renders the main dashboard
@@ -96,6 +265,47 @@ func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error getting last weather data: %v", err)
 	}
 
+	sensors, err := h.getSensors()
+	if err != nil {
+		log.Printf("Error getting sensors: %v", err)
+	}
+
+	moldRiskWeek, err := h.repo.GetMoldRisk(7)
+	if err != nil {
+		log.Printf("Error getting mold risk of the last 7 days: %v", err)
+	}
+
+	moldRiskMonth, err := h.repo.GetMoldRisk(30)
+	if err != nil {
+		log.Printf("Error getting mold risk of the last 30 days: %v", err)
+	}
+
+	units := h.dashboardUnits(w, r)
+	ventilation, err := h.getRecommendations(units)
+	if err != nil {
+		log.Printf("Error getting ventilation recommendations: %v", err)
+	}
+
+	ventilationLog, err := h.getAnalyses(20)
+	if err != nil {
+		log.Printf("Error getting ventilation analyses: %v", err)
+	}
+
+	buttonEvents, err := h.getButtonEvents(10)
+	if err != nil {
+		log.Printf("Error getting button events: %v", err)
+	}
+
+	forecast, err := h.getForecast(h.forecastLocation, contracts.ResolutionHourly, 24)
+	if err != nil {
+		log.Printf("Error getting weather forecast: %v", err)
+	}
+
+	weatherAlerts, err := h.getWeatherAlerts()
+	if err != nil {
+		log.Printf("Error getting weather alerts: %v", err)
+	}
+
 	data := DashboardData{
 		Latest:            latest,
 		LastHour:          lastHour,
@@ -104,6 +314,18 @@ func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 		Last100:           last100,
 		LastButtonPushes:  lastOpenWindows,
 		LatestWeatherData: lastWeatherData,
+		Sensors:           sensors,
+		Buttons:           h.buttons,
+		SensorStatus:      h.getSensorStatus(),
+		Alerts:            h.getAlerts(),
+		MoldRiskWeek:      moldRiskWeek,
+		MoldRiskMonth:     moldRiskMonth,
+		Ventilation:       ventilation,
+		VentilationLog:    ventilationLog,
+		ButtonEvents:      buttonEvents,
+		Forecast:          forecast,
+		WeatherAlerts:     weatherAlerts,
+		Units:             units,
 	}
 
 	w.Header().Set("Content-Type", "text/html")
@@ -113,8 +335,14 @@ func (h *Handler) ServeIndex(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ServeAPI returns JSON data for API requests
+// ServeAPI returns JSON data for API requests, the temperatures in the ?units=
 func (h *Handler) ServeAPI(w http.ResponseWriter, r *http.Request) {
+	units, err := h.requestUnits(r)
+	if err != nil {
+		http.Error(w, "Invalid units", http.StatusBadRequest)
+		return
+	}
+
 	latest, err := h.repo.GetLatest()
 	if err != nil {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -127,6 +355,14 @@ func (h *Handler) ServeAPI(w http.ResponseWriter, r *http.Request) {
 	last100, _ := h.repo.GetLastN(100)
 	lastOpenWindows, _ := h.btnRepo.GetLatest()
 	lastWeatherData, _ := h.weatherRepo.GetLatest()
+	sensors, _ := h.getSensors()
+	moldRiskWeek, _ := h.repo.GetMoldRisk(7)
+	moldRiskMonth, _ := h.repo.GetMoldRisk(30)
+	ventilation, _ := h.getRecommendations(units)
+	ventilationLog, _ := h.getAnalyses(20)
+	buttonEvents, _ := h.getButtonEvents(10)
+	forecast, _ := h.getForecast(h.forecastLocation, contracts.ResolutionHourly, 24)
+	weatherAlerts, _ := h.getWeatherAlerts()
 
 	data := DashboardData{
 		Latest:            latest,
@@ -136,8 +372,62 @@ func (h *Handler) ServeAPI(w http.ResponseWriter, r *http.Request) {
 		Last100:           last100,
 		LastButtonPushes:  lastOpenWindows,
 		LatestWeatherData: lastWeatherData,
+		Sensors:           sensors,
+		Buttons:           h.buttons,
+		SensorStatus:      h.getSensorStatus(),
+		Alerts:            h.getAlerts(),
+		MoldRiskWeek:      moldRiskWeek,
+		MoldRiskMonth:     moldRiskMonth,
+		Ventilation:       ventilation,
+		VentilationLog:    ventilationLog,
+		ButtonEvents:      buttonEvents,
+		Forecast:          forecast,
+		WeatherAlerts:     weatherAlerts,
+		Units:             units,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units.dashboardData(data))
+}
+
+// ServeQuarantine returns the last 100 readings rejected by the filter pipeline
+func (h *Handler) ServeQuarantine(w http.ResponseWriter, r *http.Request) {
+	quarantined, err := h.repo.GetQuarantined(100)
+	if err != nil {
+		log.Printf("Error getting quarantined readings: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quarantined)
+}
+
+// ServeAlerts returns the active alerts
+func (h *Handler) ServeAlerts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.getAlerts())
+}
+
+// ServeMoldRisk returns the mold risk of each sensor over the last ?days= (default 7)
+func (h *Handler) ServeMoldRisk(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if value := r.URL.Query().Get("days"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days < 1 || days > 365 {
+			http.Error(w, "Invalid days", http.StatusBadRequest)
+			return
+		}
+	}
+
+	risks, err := h.repo.GetMoldRisk(days)
+	if err != nil {
+		log.Printf("Error getting mold risk: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+	json.NewEncoder(w).Encode(risks)
 }
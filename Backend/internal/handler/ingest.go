@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxPushBodySize = 1 << 20
+	maxPushReadings = 1000
+)
+
+type ReadingIngester interface {
+	Ingest(readings []contracts.SensorReading) contracts.IngestResult
+}
+
+// WithPushIngestion enables POST /api/readings for remote nodes, tokens maps each API token to its node name and
+// sensors maps each sensor ID to the node allowed to push its readings
+func WithPushIngestion(ingester ReadingIngester, tokens map[string]string, sensors map[int]string) Option {
+	return func(h *Handler) error {
+		h.ingester = ingester
+		h.pushTokens = tokens
+		h.pushSensors = sensors
+		return nil
+	}
+}
+
+// ParseTokens parses the node tokens in the format "node=token,..." and returns them by token
+func ParseTokens(value string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		node, token, ok := strings.Cut(entry, "=")
+		node, token = strings.TrimSpace(node), strings.TrimSpace(token)
+		if !ok || node == "" || token == "" {
+			return nil, fmt.Errorf("invalid token definition %q, expected node=token", entry)
+		}
+		tokens[token] = node
+	}
+	return tokens, nil
+}
+
+// ParsePushSensors parses the sensors of the nodes in the format "node=sensorID,..." and returns the node by sensor
+func ParsePushSensors(value string) (map[int]string, error) {
+	sensors := map[int]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		node, id, ok := strings.Cut(entry, "=")
+		node = strings.TrimSpace(node)
+		if !ok || node == "" {
+			return nil, fmt.Errorf("invalid push sensor %q, expected node=sensorID", entry)
+		}
+		sensorID, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensor ID in %q: %v", entry, err)
+		}
+		if other, ok := sensors[sensorID]; ok && other != node {
+			return nil, fmt.Errorf("sensor %d is assigned to the nodes %s and %s", sensorID, other, node)
+		}
+		sensors[sensorID] = node
+	}
+	return sensors, nil
+}
+
+// pushTimestamp accepts unix seconds or RFC 3339
+type pushTimestamp struct {
+	time.Time
+}
+
+func (t *pushTimestamp) UnmarshalJSON(data []byte) error {
+	if seconds, err := strconv.ParseFloat(string(data), 64); err == nil {
+		whole := int64(seconds)
+		t.Time = time.Unix(whole, int64((seconds-float64(whole))*1e9))
+		return nil
+	}
+	return json.Unmarshal(data, &t.Time)
+}
+
+type pushReading struct {
+	SensorID    int            `json:"sensor_id"`
+	Temperature *float64       `json:"temperature"`
+	Humidity    *float64       `json:"humidity"`
+	Timestamp   *pushTimestamp `json:"timestamp"`
+}
+
+type pushRequest struct {
+	Readings []pushReading `json:"readings"`
+}
+
+// authenticateNode returns the node name of the bearer token
+func (h *Handler) authenticateNode(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+
+	for known, node := range h.pushTokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			return node, true
+		}
+	}
+	return "", false
+}
+
+// IngestReadings stores a batch of readings pushed by a remote node, only readings of the sensors of the node are
+// accepted. It answers 200 if every reading was stored or is a duplicate, 207 if only some were and 422 if none were.
+func (h *Handler) IngestReadings(w http.ResponseWriter, r *http.Request) {
+	if h.ingester == nil || len(h.pushTokens) == 0 {
+		http.Error(w, "Push ingestion not configured", http.StatusNotFound)
+		return
+	}
+
+	node, ok := h.authenticateNode(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req pushRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushBodySize)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Readings) == 0 || len(req.Readings) > maxPushReadings {
+		http.Error(w, fmt.Sprintf("Expected 1 to %d readings", maxPushReadings), http.StatusBadRequest)
+		return
+	}
+
+	readings := make([]contracts.SensorReading, 0, len(req.Readings))
+	var rejected []string
+	for i, reading := range req.Readings {
+		if reading.SensorID <= 0 || reading.Temperature == nil || reading.Humidity == nil {
+			http.Error(w, fmt.Sprintf("Reading %d requires sensor_id, temperature and humidity", i),
+				http.StatusBadRequest)
+			return
+		}
+		if h.pushSensors[reading.SensorID] != node {
+			rejected = append(rejected, fmt.Sprintf("sensor %d does not belong to node %s", reading.SensorID, node))
+			continue
+		}
+
+		converted := contracts.SensorReading{
+			SensorID:    reading.SensorID,
+			Temperature: *reading.Temperature,
+			Humidity:    *reading.Humidity,
+		}
+		if reading.Timestamp != nil {
+			converted.Timestamp = reading.Timestamp.Time
+		}
+		readings = append(readings, converted)
+	}
+
+	var result contracts.IngestResult
+	if len(readings) > 0 {
+		result = h.ingester.Ingest(readings)
+	}
+	result.Errors = append(rejected, result.Errors...)
+	log.Printf("Node %s pushed %d readings: %d stored, %d duplicates, %d quarantined, %d errors",
+		node, len(req.Readings), result.Stored, result.Duplicates, result.Quarantined, len(result.Errors))
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 || result.Quarantined > 0 {
+		status = http.StatusMultiStatus
+		if result.Stored == 0 && result.Duplicates == 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"fmt"
+	"net/http"
+)
+
+// Units are the units temperatures are presented in, they are always stored in °C
+type Units string
+
+const (
+	UnitsMetric   Units = "metric"
+	UnitsImperial Units = "imperial"
+)
+
+// unitsCookie remembers the units chosen on the dashboard
+const unitsCookie = "units"
+
+func ParseUnits(value string) (Units, error) {
+	switch units := Units(value); units {
+	case UnitsMetric, UnitsImperial:
+		return units, nil
+	}
+	return "", fmt.Errorf("invalid units %q, expected %s or %s", value, UnitsMetric, UnitsImperial)
+}
+
+// WithUnits sets the units temperatures are presented in unless a request asks for others
+func WithUnits(units string) Option {
+	return func(h *Handler) error {
+		var err error
+		h.units, err = ParseUnits(units)
+		return err
+	}
+}
+
+// requestUnits returns the ?units= of the request, falling back to the default units
+func (h *Handler) requestUnits(r *http.Request) (Units, error) {
+	if value := r.URL.Query().Get("units"); value != "" {
+		return ParseUnits(value)
+	}
+	return h.defaultUnits(), nil
+}
+
+func (h *Handler) defaultUnits() Units {
+	if h.units == "" {
+		return UnitsMetric
+	}
+	return h.units
+}
+
+// Temperature converts a temperature in °C
+func (u Units) Temperature(celsius float64) float64 {
+	if u == UnitsImperial {
+		return celsius*9/5 + 32
+	}
+	return celsius
+}
+
+// TemperatureDifference converts a temperature difference in °C
+func (u Units) TemperatureDifference(celsius float64) float64 {
+	if u == UnitsImperial {
+		return celsius * 9 / 5
+	}
+	return celsius
+}
+
+// TemperatureUnit returns the symbol of the temperature unit
+func (u Units) TemperatureUnit() string {
+	if u == UnitsImperial {
+		return "°F"
+	}
+	return "°C"
+}
+
+// FormatTemperature formats a temperature in °C with the unit symbol
+func (u Units) FormatTemperature(celsius float64) string {
+	return fmt.Sprintf("%.1f%s", u.Temperature(celsius), u.TemperatureUnit())
+}
+
+func (u Units) temperature32(celsius float32) float32 {
+	return float32(u.Temperature(float64(celsius)))
+}
+
+// dashboardUnits returns the units chosen with ?units= on the dashboard, which are remembered in a cookie,
+// falling back to the default units
+func (h *Handler) dashboardUnits(w http.ResponseWriter, r *http.Request) Units {
+	if units, err := ParseUnits(r.URL.Query().Get("units")); err == nil {
+		http.SetCookie(w, &http.Cookie{Name: unitsCookie, Value: string(units), Path: "/", MaxAge: 365 * 24 * 60 * 60})
+		return units
+	}
+	if cookie, err := r.Cookie(unitsCookie); err == nil {
+		if units, err := ParseUnits(cookie.Value); err == nil {
+			return units
+		}
+	}
+	return h.defaultUnits()
+}
+
+// Temperature converts a temperature in °C stored as float32 or float64 to the units of the dashboard
+func (d DashboardData) Temperature(celsius any) float64 {
+	return d.Units.Temperature(toFloat(celsius))
+}
+
+// TemperatureDifference converts a temperature difference in °C to the units of the dashboard
+func (d DashboardData) TemperatureDifference(celsius any) float64 {
+	return d.Units.TemperatureDifference(toFloat(celsius))
+}
+
+func toFloat(value any) float64 {
+	switch v := value.(type) {
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// the converters below return converted copies, the stored values stay in °C
+
+func (u Units) weatherData(data []*contracts.WeatherData) []*contracts.WeatherData {
+	if u != UnitsImperial {
+		return data
+	}
+	converted := make([]*contracts.WeatherData, 0, len(data))
+	for _, datum := range data {
+		c := *datum
+		c.Temperature = u.temperature32(c.Temperature)
+		c.FeelsLike = u.temperature32(c.FeelsLike)
+		c.DewPoint = u.temperature32(c.DewPoint)
+		c.HeatIndex = u.temperature32(c.HeatIndex)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+func (u Units) weatherAggregates(aggregates []*contracts.WeatherAggregate) []*contracts.WeatherAggregate {
+	if u != UnitsImperial {
+		return aggregates
+	}
+	converted := make([]*contracts.WeatherAggregate, 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		c := *aggregate
+		c.TemperatureMin = u.temperature32(c.TemperatureMin)
+		c.TemperatureAvg = u.temperature32(c.TemperatureAvg)
+		c.TemperatureMax = u.temperature32(c.TemperatureMax)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+func (u Units) forecasts(forecasts []*contracts.WeatherForecast) []*contracts.WeatherForecast {
+	if u != UnitsImperial {
+		return forecasts
+	}
+	converted := make([]*contracts.WeatherForecast, 0, len(forecasts))
+	for _, forecast := range forecasts {
+		c := *forecast
+		c.Temperature = u.temperature32(c.Temperature)
+		c.TemperatureMin = u.temperature32(c.TemperatureMin)
+		c.TemperatureMax = u.temperature32(c.TemperatureMax)
+		c.DewPoint = u.temperature32(c.DewPoint)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+// convertAverages converts the temperatures of the averages maps of the repositories, keyed by sensor ID or
+// location name
+func convertAverages[K comparable](u Units, averages map[K]map[string]float64) map[K]map[string]float64 {
+	if u != UnitsImperial {
+		return averages
+	}
+	converted := make(map[K]map[string]float64, len(averages))
+	for name, values := range averages {
+		c := make(map[string]float64, len(values))
+		for key, value := range values {
+			switch key {
+			case "temperature", "dew_point", "heat_index":
+				value = u.Temperature(value)
+			}
+			c[key] = value
+		}
+		converted[name] = c
+	}
+	return converted
+}
+
+func (u Units) sensorReadings(readings []*contracts.SensorReading) []*contracts.SensorReading {
+	if u != UnitsImperial {
+		return readings
+	}
+	converted := make([]*contracts.SensorReading, 0, len(readings))
+	for _, reading := range readings {
+		c := *reading
+		c.Temperature = u.Temperature(c.Temperature)
+		c.RawTemperature = u.Temperature(c.RawTemperature)
+		c.DewPoint = u.Temperature(c.DewPoint)
+		c.HeatIndex = u.Temperature(c.HeatIndex)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+func (u Units) recommendations(
+	recommendations []*contracts.VentilationRecommendation) []*contracts.VentilationRecommendation {
+	if u != UnitsImperial {
+		return recommendations
+	}
+	converted := make([]*contracts.VentilationRecommendation, 0, len(recommendations))
+	for _, recommendation := range recommendations {
+		c := *recommendation
+		c.IndoorTemperature = u.Temperature(c.IndoorTemperature)
+		c.OutdoorTemperature = u.Temperature(c.OutdoorTemperature)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+func (u Units) analyses(analyses []*contracts.VentilationAnalysis) []*contracts.VentilationAnalysis {
+	if u != UnitsImperial {
+		return analyses
+	}
+	converted := make([]*contracts.VentilationAnalysis, 0, len(analyses))
+	for _, analysis := range analyses {
+		c := *analysis
+		c.Before.Temperature = u.Temperature(c.Before.Temperature)
+		c.During.Temperature = u.Temperature(c.During.Temperature)
+		if c.After != nil {
+			after := *c.After
+			after.Temperature = u.Temperature(after.Temperature)
+			c.After = &after
+		}
+		c.TemperatureLoss = u.TemperatureDifference(c.TemperatureLoss)
+		converted = append(converted, &c)
+	}
+	return converted
+}
+
+// dashboardData converts all temperatures of the dashboard data for the JSON API, the template converts them
+// itself with Temperature
+func (u Units) dashboardData(data DashboardData) DashboardData {
+	data.Latest = u.sensorReadings(data.Latest)
+	data.LastHour = convertAverages(u, data.LastHour)
+	data.Today = convertAverages(u, data.Today)
+	data.ThisWeek = convertAverages(u, data.ThisWeek)
+	data.Last100 = u.sensorReadings(data.Last100)
+	data.LatestWeatherData = u.weatherData(data.LatestWeatherData)
+	data.Ventilation = u.recommendations(data.Ventilation)
+	data.VentilationLog = u.analyses(data.VentilationLog)
+	data.Forecast = u.forecasts(data.Forecast)
+	return data
+}
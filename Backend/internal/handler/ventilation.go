@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+type VentilationRecommender interface {
+	// Recommend formats the temperatures in the reasons with formatTemperature
+	Recommend(formatTemperature func(celsius float64) string) ([]*contracts.VentilationRecommendation, error)
+}
+
+type VentilationAnalysisRepository interface {
+	GetAll(offset int, limit int) ([]*contracts.VentilationAnalysis, error)
+	DeleteByButtonReading(buttonReadingID int64) error
+}
+
+// WithVentilationRecommender shows the ventilation recommendations and enables GET /api/ventilation
+func WithVentilationRecommender(recommender VentilationRecommender) Option {
+	return func(h *Handler) error {
+		h.recommender = recommender
+		return nil
+	}
+}
+
+// WithVentilationAnalyses shows the ventilation history and enables GET /api/ventilation/analyses
+func WithVentilationAnalyses(repo VentilationAnalysisRepository) Option {
+	return func(h *Handler) error {
+		h.analysisRepo = repo
+		return nil
+	}
+}
+
+func (h *Handler) getAnalyses(limit int) ([]*contracts.VentilationAnalysis, error) {
+	if h.analysisRepo == nil {
+		return nil, nil
+	}
+	return h.analysisRepo.GetAll(0, limit)
+}
+
+// getRecommendations returns the recommendations with the reasons in the units, the values stay in °C
+func (h *Handler) getRecommendations(units Units) ([]*contracts.VentilationRecommendation, error) {
+	if h.recommender == nil {
+		return nil, nil
+	}
+	return h.recommender.Recommend(units.FormatTemperature)
+}
+
+// ServeVentilation returns the ventilation recommendation for each sensor in the ?units=
+func (h *Handler) ServeVentilation(w http.ResponseWriter, r *http.Request) {
+	if h.recommender == nil {
+		http.Error(w, "Ventilation recommendations not configured", http.StatusNotFound)
+		return
+	}
+
+	units, err := h.requestUnits(r)
+	if err != nil {
+		http.Error(w, "Invalid units", http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.getRecommendations(units)
+	if err != nil {
+		log.Printf("Error getting ventilation recommendations: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units.recommendations(recommendations))
+}
+
+// ServeVentilationAnalyses returns the effect of past ventilations, paginated by ?offset= and ?limit= (default 50)
+func (h *Handler) ServeVentilationAnalyses(w http.ResponseWriter, r *http.Request) {
+	if h.analysisRepo == nil {
+		http.Error(w, "Ventilation analysis not configured", http.StatusNotFound)
+		return
+	}
+
+	offset, limit := 0, 50
+	if value := r.URL.Query().Get("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	analyses, err := h.analysisRepo.GetAll(offset, limit)
+	if err != nil {
+		log.Printf("Error getting ventilation analyses: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analyses)
+}
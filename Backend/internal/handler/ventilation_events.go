@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type VentilationEventRepository interface {
+	Get(id int64) (*contracts.ButtonReading, error)
+	GetAll(offset int, limit int) ([]*contracts.ButtonReading, error)
+	GetRejected(offset int, limit int) ([]*contracts.ButtonReading, error)
+	Create(reading contracts.ButtonReading) (int64, error)
+	Update(reading contracts.ButtonReading) error
+	Delete(id int64) error
+	GetOverlapping(buttonID int, startAt time.Time, endAt time.Time, excludeID int64) ([]*contracts.ButtonReading, error)
+}
+
+// WithVentilationEvents enables listing, creating, updating and deleting ventilations via /api/ventilation/events
+func WithVentilationEvents(repo VentilationEventRepository) Option {
+	return func(h *Handler) error {
+		h.ventilationEvents = repo
+		return nil
+	}
+}
+
+type ventilationEventRequest struct {
+	ButtonID int        `json:"button_id"`
+	StartAt  *time.Time `json:"start_at"`
+	EndAt    *time.Time `json:"end_at"`
+}
+
+// ServeVentilationEvents returns the finished ventilations, paginated by ?offset= and ?limit= (default 50),
+// with ?rejected=true the ventilations rejected by the session rules
+func (h *Handler) ServeVentilationEvents(w http.ResponseWriter, r *http.Request) {
+	if h.ventilationEvents == nil {
+		http.Error(w, "Ventilation events not configured", http.StatusNotFound)
+		return
+	}
+
+	offset, limit := 0, 50
+	if value := r.URL.Query().Get("offset"); value != "" {
+		var err error
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > 1000 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rejected := false
+	if value := r.URL.Query().Get("rejected"); value != "" {
+		var err error
+		rejected, err = strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, "Invalid rejected", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var events []*contracts.ButtonReading
+	var err error
+	if rejected {
+		events, err = h.ventilationEvents.GetRejected(offset, limit)
+	} else {
+		events, err = h.ventilationEvents.GetAll(offset, limit)
+	}
+	if err != nil {
+		log.Printf("Error getting ventilation events: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(events)
+}
+
+// CreateVentilationEvent stores a manually entered ventilation
+func (h *Handler) CreateVentilationEvent(w http.ResponseWriter, r *http.Request) {
+	if h.ventilationEvents == nil {
+		http.Error(w, "Ventilation events not configured", http.StatusNotFound)
+		return
+	}
+
+	event, ok := h.decodeVentilationEvent(w, r)
+	if !ok || !h.checkVentilationOverlap(w, event) {
+		return
+	}
+
+	id, err := h.ventilationEvents.Create(*event)
+	if err != nil {
+		log.Printf("Error creating ventilation event: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	event.ID = id
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(event)
+}
+
+// UpdateVentilationEvent replaces the button and times of a finished ventilation, it is marked as entered manually
+// and a rejected ventilation is accepted
+func (h *Handler) UpdateVentilationEvent(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.findVentilationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	event, ok := h.decodeVentilationEvent(w, r)
+	if !ok {
+		return
+	}
+	event.ID = existing.ID
+	if !h.checkVentilationOverlap(w, event) {
+		return
+	}
+
+	if err := h.ventilationEvents.Update(*event); err != nil {
+		log.Printf("Error updating ventilation event %d: %v", event.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.resetAnalyses(event.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+// DeleteVentilationEvent removes a finished ventilation and its analyses
+func (h *Handler) DeleteVentilationEvent(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.findVentilationEvent(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ventilationEvents.Delete(existing.ID); err != nil {
+		log.Printf("Error deleting ventilation event %d: %v", existing.ID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	h.resetAnalyses(existing.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findVentilationEvent loads the finished ventilation of the {id} path value, it writes the error response otherwise
+func (h *Handler) findVentilationEvent(w http.ResponseWriter, r *http.Request) (*contracts.ButtonReading, bool) {
+	if h.ventilationEvents == nil {
+		http.Error(w, "Ventilation events not configured", http.StatusNotFound)
+		return nil, false
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	event, err := h.ventilationEvents.Get(id)
+	if err != nil {
+		log.Printf("Error getting ventilation event %d: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if event == nil {
+		http.Error(w, "Ventilation event not found", http.StatusNotFound)
+		return nil, false
+	}
+	// the button app still holds the session
+	if event.EndAt == nil {
+		http.Error(w, "Ventilation event is in progress", http.StatusConflict)
+		return nil, false
+	}
+	return event, true
+}
+
+// decodeVentilationEvent validates the request body, it writes the error response otherwise
+func (h *Handler) decodeVentilationEvent(w http.ResponseWriter, r *http.Request) (*contracts.ButtonReading, bool) {
+	var req ventilationEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	if req.ButtonID <= 0 {
+		http.Error(w, "button_id is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if !h.isButton(req.ButtonID) {
+		http.Error(w, "Unknown button_id", http.StatusBadRequest)
+		return nil, false
+	}
+	if req.StartAt == nil || req.EndAt == nil {
+		http.Error(w, "start_at and end_at are required", http.StatusBadRequest)
+		return nil, false
+	}
+	if !req.EndAt.After(*req.StartAt) {
+		http.Error(w, "end_at must be after start_at", http.StatusBadRequest)
+		return nil, false
+	}
+	if req.EndAt.After(time.Now()) {
+		http.Error(w, "end_at must not be in the future", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// stored in the local time zone like the ventilations recorded by the buttons, so they compare
+	endAt := req.EndAt.Local()
+	return &contracts.ButtonReading{
+		ButtonID: req.ButtonID,
+		StartAt:  req.StartAt.Local(),
+		EndAt:    &endAt,
+		Source:   contracts.SourceManual,
+	}, true
+}
+
+// checkVentilationOverlap rejects an event overlapping another ventilation of the same button
+func (h *Handler) checkVentilationOverlap(w http.ResponseWriter, event *contracts.ButtonReading) bool {
+	overlapping, err := h.ventilationEvents.GetOverlapping(event.ButtonID, event.StartAt, *event.EndAt, event.ID)
+	if err != nil {
+		log.Printf("Error checking overlapping ventilation events: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if len(overlapping) == 0 {
+		return true
+	}
+
+	ids := make([]string, len(overlapping))
+	for i, other := range overlapping {
+		ids[i] = strconv.FormatInt(other.ID, 10)
+	}
+	http.Error(w, fmt.Sprintf("Overlaps ventilation event %s", strings.Join(ids, ", ")), http.StatusConflict)
+	return false
+}
+
+// isButton checks the button against the configured buttons, any button is accepted without configuration
+func (h *Handler) isButton(buttonID int) bool {
+	if len(h.buttons) == 0 {
+		return true
+	}
+	for _, button := range h.buttons {
+		if button.ID == buttonID {
+			return true
+		}
+	}
+	return false
+}
+
+// resetAnalyses removes the analyses of a changed ventilation, a still recent one is analyzed again
+func (h *Handler) resetAnalyses(buttonReadingID int64) {
+	if h.analysisRepo == nil {
+		return
+	}
+	if err := h.analysisRepo.DeleteByButtonReading(buttonReadingID); err != nil {
+		log.Printf("Error deleting analyses of ventilation event %d: %v", buttonReadingID, err)
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+type ForecastRepository interface {
+	GetLatest(name string, resolution string, from time.Time) ([]*contracts.WeatherForecast, error)
+}
+
+type WeatherAlertRepository interface {
+	GetActive(at time.Time) ([]*contracts.WeatherAlert, error)
+}
+
+// WithWeatherAlerts shows the active official weather warnings and enables GET /api/weather/alerts
+func WithWeatherAlerts(repo WeatherAlertRepository) Option {
+	return func(h *Handler) error {
+		h.weatherAlertRepo = repo
+		return nil
+	}
+}
+
+func (h *Handler) getWeatherAlerts() ([]*contracts.WeatherAlert, error) {
+	if h.weatherAlertRepo == nil {
+		return nil, nil
+	}
+	return h.weatherAlertRepo.GetActive(time.Now())
+}
+
+// ServeWeatherAlerts returns the active and upcoming official weather warnings of all locations
+func (h *Handler) ServeWeatherAlerts(w http.ResponseWriter, r *http.Request) {
+	if h.weatherAlertRepo == nil {
+		http.Error(w, "Weather alerts not configured", http.StatusNotFound)
+		return
+	}
+
+	alerts, err := h.getWeatherAlerts()
+	if err != nil {
+		log.Printf("Error getting weather alerts: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
+
+// WithForecasts shows the hourly forecast of the location and enables GET /api/weather/forecast,
+// which returns the forecast of the location unless another one is requested
+func WithForecasts(repo ForecastRepository, location string) Option {
+	return func(h *Handler) error {
+		h.forecastRepo = repo
+		h.forecastLocation = location
+		return nil
+	}
+}
+
+// getForecast returns the latest forecast of the location from the current hour on, at most limit entries
+func (h *Handler) getForecast(location string, resolution string, limit int) ([]*contracts.WeatherForecast, error) {
+	if h.forecastRepo == nil {
+		return nil, nil
+	}
+
+	from := time.Now().Truncate(time.Hour)
+	if resolution == contracts.ResolutionDaily {
+		year, month, day := time.Now().Date()
+		from = time.Date(year, month, day, 0, 0, 0, 0, time.Local)
+	}
+	forecasts, err := h.forecastRepo.GetLatest(location, resolution, from)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(forecasts) > limit {
+		forecasts = forecasts[:limit]
+	}
+	return forecasts, nil
+}
+
+// ServeForecast returns the latest ?resolution=hourly (default) or daily forecast of the ?location= in the ?units=
+func (h *Handler) ServeForecast(w http.ResponseWriter, r *http.Request) {
+	if h.forecastRepo == nil {
+		http.Error(w, "Weather forecast not configured", http.StatusNotFound)
+		return
+	}
+	units, err := h.requestUnits(r)
+	if err != nil {
+		http.Error(w, "Invalid units", http.StatusBadRequest)
+		return
+	}
+
+	resolution := contracts.ResolutionHourly
+	if value := r.URL.Query().Get("resolution"); value != "" {
+		if value != contracts.ResolutionHourly && value != contracts.ResolutionDaily {
+			http.Error(w, "Invalid resolution", http.StatusBadRequest)
+			return
+		}
+		resolution = value
+	}
+
+	location := h.forecastLocation
+	if value := r.URL.Query().Get("location"); value != "" {
+		location = value
+	}
+
+	forecasts, err := h.getForecast(location, resolution, 0)
+	if err != nil {
+		log.Printf("Error getting weather forecast: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(units.forecasts(forecasts))
+}
+
+// ServeWeatherHistory returns the weather data of the ?location= (default all) between ?from= and ?to= (RFC 3339,
+// default the last 24 hours), as ?resolution=hourly or daily aggregates or, without one, as stored, in the ?units=
+func (h *Handler) ServeWeatherHistory(w http.ResponseWriter, r *http.Request) {
+	units, err := h.requestUnits(r)
+	if err != nil {
+		http.Error(w, "Invalid units", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	to := time.Now()
+	if value := query.Get("to"); value != "" {
+		to, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			http.Error(w, "Invalid to", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to.Add(-24 * time.Hour)
+	if value := query.Get("from"); value != "" {
+		from, err = time.Parse(time.RFC3339, value)
+		if err != nil || from.After(to) {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the weather is stored in the local time zone and compared as text
+	from, to = from.Local(), to.Local()
+
+	location := query.Get("location")
+	var data any
+	switch resolution := query.Get("resolution"); resolution {
+	case "":
+		var readings []*contracts.WeatherData
+		readings, err = h.weatherRepo.GetInBetween(location, from, to)
+		data = units.weatherData(readings)
+	case contracts.ResolutionHourly, contracts.ResolutionDaily:
+		var aggregates []*contracts.WeatherAggregate
+		aggregates, err = h.weatherRepo.GetAggregates(location, resolution, from, to)
+		data = units.weatherAggregates(aggregates)
+	default:
+		http.Error(w, "Invalid resolution", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting weather history: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// ServeWeatherAverages returns the average weather of each location in the last hour, today and this week
+// in the ?units=
+func (h *Handler) ServeWeatherAverages(w http.ResponseWriter, r *http.Request) {
+	units, err := h.requestUnits(r)
+	if err != nil {
+		http.Error(w, "Invalid units", http.StatusBadRequest)
+		return
+	}
+
+	averages := make(map[string]map[string]map[string]float64)
+	for period, get := range map[string]func() (map[string]map[string]float64, error){
+		"last_hour": h.weatherRepo.GetAverageLastHour,
+		"today":     h.weatherRepo.GetAverageToday,
+		"this_week": h.weatherRepo.GetAverageThisWeek,
+	} {
+		average, err := get()
+		if err != nil {
+			log.Printf("Error getting weather averages: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		averages[period] = convertAverages(units, average)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(averages)
+}
@@ -1,27 +1,85 @@
 package sensor
 
 import (
+	"BeRoHuTe/internal/contracts"
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sort"
+	"sync"
 	"time"
 )
 
+type AppOption func(*DHTApp) error
+
+// WithCalibrator applies the calibration valid at the timestamp of each reading before it is stored
+func WithCalibrator(calibrator *Calibrator) AppOption {
+	return func(app *DHTApp) error {
+		app.calibrator = calibrator
+		return nil
+	}
+}
+
+// WithPipeline validates and filters the calibrated readings, rejected readings are quarantined
+func WithPipeline(pipeline *Pipeline) AppOption {
+	return func(app *DHTApp) error {
+		app.pipeline = pipeline
+		return nil
+	}
+}
+
+// WithRegistry only accepts pushed readings for enabled sensors with the source "push"
+func WithRegistry(registry *Registry) AppOption {
+	return func(app *DHTApp) error {
+		app.registry = registry
+		return nil
+	}
+}
+
+// WithStalenessMonitor reports sensors whose values did not advance for too long
+func WithStalenessMonitor(monitor *StalenessMonitor) AppOption {
+	return func(app *DHTApp) error {
+		app.monitor = monitor
+		return nil
+	}
+}
+
 type DHTApp struct {
 	service        Service
 	repo           *Repository
+	calibrator     *Calibrator
+	pipeline       *Pipeline
+	monitor        *StalenessMonitor
+	registry       *Registry
 	stop           chan bool
 	lastTimestamps map[int]time.Time
 	interval       time.Duration
+
+	// processMu guards the de-duplication and the filters, readings are pushed concurrently
+	processMu sync.Mutex
+
+	statusMu sync.RWMutex
+	status   map[int]*contracts.SensorStatus
 }
 
-func NewApp(readInterval time.Duration, sensorService Service, repo *Repository) *DHTApp {
-	return &DHTApp{
+func NewApp(readInterval time.Duration, sensorService Service, repo *Repository, options ...AppOption) (*DHTApp, error) {
+	app := &DHTApp{
 		service:        sensorService,
 		repo:           repo,
 		stop:           make(chan bool),
 		lastTimestamps: map[int]time.Time{},
 		interval:       readInterval,
+		status:         map[int]*contracts.SensorStatus{},
+	}
+
+	for _, option := range options {
+		if err := option(app); err != nil {
+			return nil, err
+		}
 	}
+
+	return app, nil
 }
 
 func (sensorApp *DHTApp) Start(ctx context.Context, execDirectly bool) {
@@ -30,6 +88,10 @@ func (sensorApp *DHTApp) Start(ctx context.Context, execDirectly bool) {
 	if execDirectly {
 		startInterval = time.Millisecond
 	}
+	if len(sensorApp.lastTimestamps) == 0 {
+		sensorApp.loadLastTimestamps()
+	}
+
 	ticker := time.NewTicker(startInterval)
 	go func() {
 		defer ticker.Stop()
@@ -50,29 +112,275 @@ func (sensorApp *DHTApp) Start(ctx context.Context, execDirectly bool) {
 }
 
 func (sensorApp *DHTApp) performReading() {
-	readings, err := sensorApp.service.ReadAllSensors()
+	if sensorApp.monitor != nil {
+		defer sensorApp.monitor.Check(time.Now())
+	}
+
+	results, err := sensorApp.service.ReadAllSensors()
 	if err != nil {
 		log.Printf("Error reading sensors: %v", err)
 		return
 	}
 
+	readings := make([]*Reading, 0, len(results))
+	for _, result := range results {
+		if sensorApp.monitor != nil {
+			sensorApp.monitor.Observe(result.SensorID)
+		}
+		if result.Err != nil {
+			log.Printf("Error reading sensor %d: %v", result.SensorID, result.Err)
+			sensorApp.recordFailure(result.SensorID, result.Err)
+			continue
+		}
+		sensorApp.recordSuccess(result.SensorID)
+		readings = append(readings, result.Reading)
+	}
+
+	processed, _ := sensorApp.process(readings)
+
+	if acknowledger, ok := sensorApp.service.(Acknowledger); ok {
+		if err := acknowledger.Acknowledge(processed); err != nil {
+			log.Printf("Error acknowledging readings: %v", err)
+		}
+	}
+}
+
+// Ingest stores readings pushed by remote nodes, they pass the same de-duplication,
+// calibration and validation as the readings of the service
+func (sensorApp *DHTApp) Ingest(readings []contracts.SensorReading) contracts.IngestResult {
+	batch := make([]*Reading, 0, len(readings))
+	var invalid []string
 	for _, reading := range readings {
+		if err := sensorApp.checkPushSensor(reading.SensorID); err != nil {
+			invalid = append(invalid, err.Error())
+			continue
+		}
+
+		// stored in the local time zone like the readings of the services, so the time ranges compare as text
+		timestamp := reading.Timestamp.Local()
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+
+		if sensorApp.monitor != nil {
+			sensorApp.monitor.Observe(reading.SensorID)
+		}
+		sensorApp.recordSuccess(reading.SensorID)
+		batch = append(batch, &Reading{
+			SensorID:    reading.SensorID,
+			Temperature: reading.Temperature,
+			Humidity:    reading.Humidity,
+			Timestamp:   timestamp,
+		})
+	}
+
+	// the batch may contain several readings per sensor, de-duplication expects them in order
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].Timestamp.Before(batch[j].Timestamp)
+	})
+
+	_, result := sensorApp.process(batch)
+	result.Errors = append(invalid, result.Errors...)
+	if sensorApp.monitor != nil {
+		sensorApp.monitor.Check(time.Now())
+	}
+
+	return result
+}
+
+func (sensorApp *DHTApp) checkPushSensor(sensorID int) error {
+	if sensorApp.registry == nil {
+		return nil
+	}
+
+	sensor, err := sensorApp.registry.Get(sensorID)
+	if err != nil {
+		return err
+	}
+	if !sensor.Enabled {
+		return fmt.Errorf("sensor %d is disabled", sensorID)
+	}
+	if sensor.Source != SourcePush {
+		return fmt.Errorf("sensor %d is read from %s and does not accept pushed readings", sensorID, sensor.Source)
+	}
+	return nil
+}
+
+// process de-duplicates, calibrates, filters and stores the readings. It returns the readings which were
+// handled completely, readings which failed to be stored are retried with the next reading.
+func (sensorApp *DHTApp) process(readings []*Reading) ([]*Reading, contracts.IngestResult) {
+	sensorApp.processMu.Lock()
+	defer sensorApp.processMu.Unlock()
+
+	var stats contracts.IngestResult
+
+	var calibrations Calibrations
+	if sensorApp.calibrator != nil {
+		var err error
+		calibrations, err = sensorApp.calibrator.All()
+		if err != nil {
+			log.Printf("Error loading calibrations, storing uncalibrated values: %v", err)
+		}
+	}
+
+	processed := make([]*Reading, 0, len(readings))
+	// sensors with a reading that failed to be stored, their later readings are retried after it, otherwise the
+	// de-duplication would drop the failed one when it is delivered again
+	failed := make(map[int]bool)
+	for _, reading := range readings {
+		if failed[reading.SensorID] {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("sensor %d: reading skipped after a failed save",
+				reading.SensorID))
+			continue
+		}
+
 		lastTimestamp, ok := sensorApp.lastTimestamps[reading.SensorID]
 		if ok && (lastTimestamp.After(reading.Timestamp) ||
 			lastTimestamp.Equal(reading.Timestamp)) {
+			stats.Duplicates++
+			processed = append(processed, reading)
 			continue
 		}
 
-		sensorApp.lastTimestamps[reading.SensorID] = reading.Timestamp
+		if sensorApp.monitor != nil {
+			sensorApp.monitor.Fresh(reading.SensorID, reading.Timestamp)
+		}
+
+		calibrated := *reading
+		// a delayed reading is calibrated with the calibration valid when it was taken
+		calibrated.Temperature, calibrated.Humidity = Calibrate(calibrations.At(reading.SensorID, reading.Timestamp),
+			reading.Temperature, reading.Humidity)
+
+		filtered, err := sensorApp.filter(&calibrated)
+		if err != nil {
+			sensorApp.quarantine(reading, &calibrated, err)
+			sensorApp.lastTimestamps[reading.SensorID] = reading.Timestamp
+			stats.Quarantined++
+			processed = append(processed, reading)
+			continue
+		}
 
 		// save to repository
-		err := sensorApp.repo.Save(reading.SensorID, reading.Temperature, reading.Humidity, reading.Timestamp)
+		err = sensorApp.repo.Save(contracts.SensorReading{
+			SensorID:       reading.SensorID,
+			Temperature:    filtered.Temperature,
+			Humidity:       filtered.Humidity,
+			RawTemperature: reading.Temperature,
+			RawHumidity:    reading.Humidity,
+			Timestamp:      reading.Timestamp,
+		})
 		if err != nil {
+			// not marked as processed, so it is retried with the next reading
 			log.Printf("Error saving reading for sensor %d: %v", reading.SensorID, err)
-		} else {
-			log.Printf("Saved: Sensor %d - Temp: %.1f°C, Humidity: %.1f%%, Time: %s",
-				reading.SensorID, reading.Temperature, reading.Humidity, reading.Timestamp.Format("15:04:05"))
+			stats.Errors = append(stats.Errors, fmt.Sprintf("sensor %d: cannot save reading", reading.SensorID))
+			failed[reading.SensorID] = true
+			continue
 		}
+
+		log.Printf("Saved: Sensor %d - Temp: %.1f°C, Humidity: %.1f%%, Time: %s",
+			reading.SensorID, filtered.Temperature, filtered.Humidity, reading.Timestamp.Format("15:04:05"))
+		sensorApp.lastTimestamps[reading.SensorID] = reading.Timestamp
+		stats.Stored++
+		processed = append(processed, reading)
+	}
+
+	return processed, stats
+}
+
+// loadLastTimestamps initializes the de-duplication with the stored readings, so values read
+// before a restart are not stored twice
+func (sensorApp *DHTApp) loadLastTimestamps() {
+	latest, err := sensorApp.repo.GetLatest()
+	if err != nil {
+		log.Printf("Error loading latest readings: %v", err)
+		return
+	}
+
+	for _, reading := range latest {
+		if reading.Timestamp.After(sensorApp.lastTimestamps[reading.SensorID]) {
+			sensorApp.lastTimestamps[reading.SensorID] = reading.Timestamp
+		}
+	}
+}
+
+func (sensorApp *DHTApp) sensorStatus(sensorID int) *contracts.SensorStatus {
+	status, ok := sensorApp.status[sensorID]
+	if !ok {
+		status = &contracts.SensorStatus{SensorID: sensorID}
+		sensorApp.status[sensorID] = status
+	}
+	return status
+}
+
+func (sensorApp *DHTApp) recordSuccess(sensorID int) {
+	sensorApp.statusMu.Lock()
+	defer sensorApp.statusMu.Unlock()
+
+	status := sensorApp.sensorStatus(sensorID)
+	status.TotalReads++
+	status.ConsecutiveFailures = 0
+	status.LastSuccessAt = time.Now()
+}
+
+func (sensorApp *DHTApp) recordFailure(sensorID int, err error) {
+	sensorApp.statusMu.Lock()
+	defer sensorApp.statusMu.Unlock()
+
+	status := sensorApp.sensorStatus(sensorID)
+	status.TotalReads++
+	status.TotalFailures++
+	status.ConsecutiveFailures++
+	status.LastError = err.Error()
+	status.LastErrorAt = time.Now()
+}
+
+// Status returns the read statistics of all sensors since the application started
+func (sensorApp *DHTApp) Status() []contracts.SensorStatus {
+	sensorApp.statusMu.RLock()
+	defer sensorApp.statusMu.RUnlock()
+
+	result := make([]contracts.SensorStatus, 0, len(sensorApp.status))
+	for _, status := range sensorApp.status {
+		current := *status
+		if sensorApp.monitor != nil {
+			current.LastFreshAt, current.Stale = sensorApp.monitor.State(current.SensorID)
+		}
+		result = append(result, current)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].SensorID < result[j].SensorID
+	})
+	return result
+}
+
+func (sensorApp *DHTApp) filter(reading *Reading) (*Reading, error) {
+	if sensorApp.pipeline == nil {
+		return reading, nil
+	}
+	return sensorApp.pipeline.Process(reading)
+}
+
+func (sensorApp *DHTApp) quarantine(raw *Reading, calibrated *Reading, err error) {
+	var rejectErr *RejectError
+	if !errors.As(err, &rejectErr) {
+		log.Printf("Error filtering reading for sensor %d: %v", raw.SensorID, err)
+		return
+	}
+
+	log.Printf("Quarantined: Sensor %d - %s", raw.SensorID, rejectErr.Reason)
+	err = sensorApp.repo.Quarantine(contracts.QuarantinedReading{
+		SensorID:       raw.SensorID,
+		Temperature:    calibrated.Temperature,
+		Humidity:       calibrated.Humidity,
+		RawTemperature: raw.Temperature,
+		RawHumidity:    raw.Humidity,
+		Timestamp:      raw.Timestamp,
+		Filter:         rejectErr.Filter,
+		Reason:         rejectErr.Reason,
+		CreatedAt:      time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error quarantining reading for sensor %d: %v", raw.SensorID, err)
 	}
 }
 
@@ -0,0 +1,64 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"testing"
+	"time"
+)
+
+func TestIngestStoresTimestampsInTheLocalTimeZone(t *testing.T) {
+	repo, err := New(newTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := NewApp(time.Minute, nil, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a text comparison with the offset of the node would place the readings three hours earlier
+	pushed := time.Date(2026, 10, 17, 6, 30, 0, 0, time.Local)
+	result := app.Ingest([]contracts.SensorReading{
+		{SensorID: 1, Temperature: 21, Humidity: 50, Timestamp: pushed.In(foreignZone(pushed))},
+		{SensorID: 2, Temperature: 22, Humidity: 55, Timestamp: pushed.UTC()},
+	})
+	if result.Stored != 2 || len(result.Errors) != 0 {
+		t.Fatalf("Ingest() = %+v, want 2 stored readings", result)
+	}
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  int
+	}{
+		{
+			name:  "range around the local time",
+			start: pushed.Add(-time.Minute),
+			end:   pushed.Add(time.Minute),
+			want:  2,
+		},
+		{
+			name:  "range before the reading",
+			start: pushed.Add(-3 * time.Hour),
+			end:   pushed.Add(-time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readings, err := repo.GetInBetween(tt.start, tt.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(readings) != tt.want {
+				t.Fatalf("GetInBetween() returned %d readings, want %d", len(readings), tt.want)
+			}
+			for _, reading := range readings {
+				if !reading.Timestamp.Equal(pushed) {
+					t.Errorf("reading of sensor %d at %s, want %s", reading.SensorID, reading.Timestamp, pushed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// CalibrationRepository stores the history of linear calibrations per sensor
+type CalibrationRepository struct {
+	db *sql.DB
+}
+
+func NewCalibrationRepository(db *sql.DB) (*CalibrationRepository, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	repo := &CalibrationRepository{db: db}
+	if err := repo.createTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *CalibrationRepository) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS sensor_calibrations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sensor_id INTEGER NOT NULL,
+		temperature_offset REAL NOT NULL DEFAULT 0,
+		temperature_gain REAL NOT NULL DEFAULT 1,
+		humidity_offset REAL NOT NULL DEFAULT 0,
+		humidity_gain REAL NOT NULL DEFAULT 1,
+		valid_from DATETIME NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_calibration_sensor_valid_from ON sensor_calibrations(sensor_id, valid_from);
+	`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// Save stores a new calibration, older calibrations are kept as history
+func (r *CalibrationRepository) Save(cal contracts.SensorCalibration) (int64, error) {
+	query := `INSERT INTO sensor_calibrations 
+	(sensor_id, temperature_offset, temperature_gain, humidity_offset, humidity_gain, valid_from, created_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(query, cal.SensorID, cal.TemperatureOffset, cal.TemperatureGain,
+		cal.HumidityOffset, cal.HumidityGain, cal.ValidFrom, cal.CreatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetHistory returns all calibrations of a sensor, the newest first
+func (r *CalibrationRepository) GetHistory(sensorID int) ([]*contracts.SensorCalibration, error) {
+	query := `SELECT id, sensor_id, temperature_offset, temperature_gain, humidity_offset, humidity_gain, valid_from, created_at
+	FROM sensor_calibrations WHERE sensor_id = ? ORDER BY valid_from DESC, id DESC`
+	return r.queryCalibrations(query, sensorID)
+}
+
+// GetAll returns the calibrations of all sensors, the newest first
+func (r *CalibrationRepository) GetAll() ([]*contracts.SensorCalibration, error) {
+	query := `SELECT id, sensor_id, temperature_offset, temperature_gain, humidity_offset, humidity_gain, valid_from, created_at
+	FROM sensor_calibrations ORDER BY valid_from DESC, id DESC`
+	return r.queryCalibrations(query)
+}
+
+func (r *CalibrationRepository) queryCalibrations(query string, args ...interface{}) ([]*contracts.SensorCalibration, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calibrations []*contracts.SensorCalibration
+	for rows.Next() {
+		var cal contracts.SensorCalibration
+		err := rows.Scan(&cal.ID, &cal.SensorID, &cal.TemperatureOffset, &cal.TemperatureGain,
+			&cal.HumidityOffset, &cal.HumidityGain, &cal.ValidFrom, &cal.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		calibrations = append(calibrations, &cal)
+	}
+
+	return calibrations, rows.Err()
+}
+
+// Calibrate applies the linear calibration (value * gain + offset) to raw values
+func Calibrate(cal *contracts.SensorCalibration, temperature, humidity float64) (float64, float64) {
+	if cal == nil {
+		return temperature, humidity
+	}
+
+	temperature = temperature*cal.TemperatureGain + cal.TemperatureOffset
+	humidity = math.Min(math.Max(humidity*cal.HumidityGain+cal.HumidityOffset, 0), 100)
+
+	return math.Round(temperature*10) / 10, math.Round(humidity*10) / 10
+}
+
+// Calibrator manages calibrations and keeps the stored readings in sync with them
+type Calibrator struct {
+	calibrations *CalibrationRepository
+	readings     *Repository
+}
+
+func NewCalibrator(calibrations *CalibrationRepository, readings *Repository) *Calibrator {
+	return &Calibrator{
+		calibrations: calibrations,
+		readings:     readings,
+	}
+}
+
+// Calibrations are the calibrations of all sensors, the newest first
+type Calibrations []*contracts.SensorCalibration
+
+// At returns the calibration of the sensor valid at the given time, nil if it was not calibrated then
+func (c Calibrations) At(sensorID int, at time.Time) *contracts.SensorCalibration {
+	for _, cal := range c {
+		if cal.SensorID == sensorID && !cal.ValidFrom.After(at) {
+			return cal
+		}
+	}
+	return nil
+}
+
+// All returns the calibrations of all sensors, so a batch of readings is calibrated at their timestamps with a
+// single query
+func (c *Calibrator) All() (Calibrations, error) {
+	return c.calibrations.GetAll()
+}
+
+// History returns all calibrations of a sensor or of all sensors if sensorID is 0
+func (c *Calibrator) History(sensorID int) ([]*contracts.SensorCalibration, error) {
+	if sensorID == 0 {
+		return c.calibrations.GetAll()
+	}
+	return c.calibrations.GetHistory(sensorID)
+}
+
+// Add stores a new calibration. With recompute all readings from ValidFrom up to the next
+// calibration of the sensor are recalculated from their raw values, the number of updated readings is returned.
+func (c *Calibrator) Add(cal contracts.SensorCalibration, recompute bool) (*contracts.SensorCalibration, int64, error) {
+	if cal.TemperatureGain == 0 || cal.HumidityGain == 0 {
+		return nil, 0, fmt.Errorf("calibration gain must not be 0")
+	}
+
+	now := time.Now()
+	cal.CreatedAt = now
+	if cal.ValidFrom.IsZero() {
+		cal.ValidFrom = now
+	}
+	// stored in the local time zone like the readings, so the time ranges compare as text
+	cal.ValidFrom = cal.ValidFrom.Local()
+
+	id, err := c.calibrations.Save(cal)
+	if err != nil {
+		return nil, 0, err
+	}
+	cal.ID = id
+
+	if !recompute {
+		return &cal, 0, nil
+	}
+
+	history, err := c.calibrations.GetHistory(cal.SensorID)
+	if err != nil {
+		return &cal, 0, err
+	}
+
+	// the history is ordered descending, so the last match is the next calibration in time
+	to := time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+	for _, other := range history {
+		if other.ID != cal.ID && other.ValidFrom.After(cal.ValidFrom) {
+			to = other.ValidFrom
+		}
+	}
+
+	count, err := c.readings.Recalibrate(cal, cal.ValidFrom, to.Local())
+	if err != nil {
+		return &cal, 0, err
+	}
+
+	return &cal, count, nil
+}
@@ -0,0 +1,181 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB returns an in-memory database that is closed with the test
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// foreignZone returns a time zone three hours behind the local time zone at the given time, timestamps in it are
+// earlier than the same instant in local time when compared as text
+func foreignZone(at time.Time) *time.Location {
+	_, offset := at.Local().Zone()
+	return time.FixedZone("foreign", offset-3*60*60)
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		cal         *contracts.SensorCalibration
+		temperature float64
+		humidity    float64
+	}{
+		{name: "no calibration", temperature: 21.34, humidity: 55.55},
+		{
+			name: "offsets",
+			cal: &contracts.SensorCalibration{TemperatureGain: 1, TemperatureOffset: -0.5, HumidityGain: 1,
+				HumidityOffset: 3},
+			temperature: 20.8,
+			humidity:    58.6,
+		},
+		{
+			name:        "gains",
+			cal:         &contracts.SensorCalibration{TemperatureGain: 1.1, HumidityGain: 0.9},
+			temperature: 23.4,
+			humidity:    50,
+		},
+		{
+			name:        "humidity clamped to 100",
+			cal:         &contracts.SensorCalibration{TemperatureGain: 1, HumidityGain: 1, HumidityOffset: 50},
+			temperature: 21.3,
+			humidity:    100,
+		},
+		{
+			name:        "humidity clamped to 0",
+			cal:         &contracts.SensorCalibration{TemperatureGain: 1, HumidityGain: 1, HumidityOffset: -60},
+			temperature: 21.3,
+			humidity:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawTemperature, rawHumidity := 21.34, 55.55
+			if tt.cal != nil {
+				rawTemperature, rawHumidity = 21.3, 55.6
+			}
+			temperature, humidity := Calibrate(tt.cal, rawTemperature, rawHumidity)
+			if temperature != tt.temperature || humidity != tt.humidity {
+				t.Errorf("Calibrate() = %v, %v, want %v, %v", temperature, humidity, tt.temperature, tt.humidity)
+			}
+		})
+	}
+}
+
+func TestCalibrationsAt(t *testing.T) {
+	day := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	calibrations := Calibrations{
+		{ID: 3, SensorID: 1, ValidFrom: day.Add(12 * time.Hour)},
+		{ID: 2, SensorID: 2, ValidFrom: day.Add(6 * time.Hour)},
+		{ID: 1, SensorID: 1, ValidFrom: day.Add(6 * time.Hour)},
+	}
+
+	tests := []struct {
+		name     string
+		sensorID int
+		at       time.Time
+		// 0 means no calibration
+		wantID int64
+	}{
+		{name: "before the first calibration", sensorID: 1, at: day.Add(5 * time.Hour)},
+		{name: "at the start of a calibration", sensorID: 1, at: day.Add(6 * time.Hour), wantID: 1},
+		{name: "between two calibrations", sensorID: 1, at: day.Add(11 * time.Hour), wantID: 1},
+		{name: "after the latest calibration", sensorID: 1, at: day.Add(13 * time.Hour), wantID: 3},
+		{name: "other sensor", sensorID: 2, at: day.Add(13 * time.Hour), wantID: 2},
+		{name: "uncalibrated sensor", sensorID: 3, at: day.Add(13 * time.Hour)},
+		{
+			name:     "other time zone",
+			sensorID: 1,
+			at:       day.Add(12 * time.Hour).In(time.FixedZone("CEST", 2*60*60)),
+			wantID:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal := calibrations.At(tt.sensorID, tt.at)
+			var id int64
+			if cal != nil {
+				id = cal.ID
+			}
+			if id != tt.wantID {
+				t.Errorf("At() = calibration %d, want %d", id, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestCalibratorAddRecomputesFromValidFrom(t *testing.T) {
+	db := newTestDB(t)
+	readings, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calibrations, err := NewCalibrationRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calibrator := NewCalibrator(calibrations, readings)
+
+	validFrom := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	before := validFrom.Add(-90 * time.Minute)
+	after := validFrom.Add(30 * time.Minute)
+	for _, timestamp := range []time.Time{before, after} {
+		reading := contracts.SensorReading{SensorID: 1, Temperature: 20, Humidity: 50, RawTemperature: 20,
+			RawHumidity: 50, Timestamp: timestamp}
+		if err := readings.Save(reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 05:00 three hours behind is 08:00 local, compared as text it would be before the reading at 06:30
+	cal, count, err := calibrator.Add(contracts.SensorCalibration{
+		SensorID:          1,
+		TemperatureGain:   1,
+		TemperatureOffset: 1,
+		HumidityGain:      1,
+		ValidFrom:         validFrom.In(foreignZone(validFrom)),
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Add() recalibrated %d readings, want 1", count)
+	}
+	if cal.ValidFrom.Location() != time.Local {
+		t.Errorf("ValidFrom is in %s, want the local time zone", cal.ValidFrom.Location())
+	}
+
+	stored, err := readings.GetInBetween(before.Add(-time.Hour), after.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("got %d readings, want 2", len(stored))
+	}
+	for _, reading := range stored {
+		want := 20.0
+		if reading.Timestamp.Equal(after) {
+			want = 21
+		}
+		if reading.Temperature != want {
+			t.Errorf("reading at %s has %.1f°C, want %.1f°C", reading.Timestamp, reading.Temperature, want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package sensor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Filter validates or smooths a reading before it is stored.
+// A rejected reading is reported with a *RejectError.
+type Filter interface {
+	Name() string
+	Apply(reading *Reading) (*Reading, error)
+}
+
+// RejectError is returned by a filter if a reading must not be stored
+type RejectError struct {
+	Filter string
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return fmt.Sprintf("reading rejected by %s: %s", e.Filter, e.Reason)
+}
+
+// Pipeline runs the filters in the given order, the first rejection stops the chain
+type Pipeline struct {
+	filters []Filter
+}
+
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+func (p *Pipeline) Process(reading *Reading) (*Reading, error) {
+	var err error
+	for _, filter := range p.filters {
+		reading, err = filter.Apply(reading)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reading, nil
+}
+
+// RangeFilter rejects physically impossible values
+type RangeFilter struct {
+	minTemperature float64
+	maxTemperature float64
+	minHumidity    float64
+	maxHumidity    float64
+}
+
+func NewRangeFilter(minTemperature, maxTemperature, minHumidity, maxHumidity float64) *RangeFilter {
+	return &RangeFilter{
+		minTemperature: minTemperature,
+		maxTemperature: maxTemperature,
+		minHumidity:    minHumidity,
+		maxHumidity:    maxHumidity,
+	}
+}
+
+func (f *RangeFilter) Name() string {
+	return "range"
+}
+
+func (f *RangeFilter) Apply(reading *Reading) (*Reading, error) {
+	if reading.Temperature < f.minTemperature || reading.Temperature > f.maxTemperature {
+		return nil, &RejectError{Filter: f.Name(), Reason: fmt.Sprintf(
+			"temperature %.1f°C outside of [%.1f, %.1f]", reading.Temperature, f.minTemperature, f.maxTemperature)}
+	}
+	if reading.Humidity < f.minHumidity || reading.Humidity > f.maxHumidity {
+		return nil, &RejectError{Filter: f.Name(), Reason: fmt.Sprintf(
+			"humidity %.1f%% outside of [%.1f, %.1f]", reading.Humidity, f.minHumidity, f.maxHumidity)}
+	}
+	return reading, nil
+}
+
+// RateFilter rejects readings changing faster than the allowed rate per minute compared to the last
+// accepted reading. Intervals shorter than a minute are treated as one minute to tolerate sensor noise.
+// Readings not newer than the last accepted one pass unchecked. A rate of 0 disables the check.
+type RateFilter struct {
+	maxTemperatureRate float64
+	maxHumidityRate    float64
+
+	mu   sync.Mutex
+	last map[int]*Reading
+}
+
+func NewRateFilter(maxTemperatureRate, maxHumidityRate float64) *RateFilter {
+	return &RateFilter{
+		maxTemperatureRate: maxTemperatureRate,
+		maxHumidityRate:    maxHumidityRate,
+		last:               map[int]*Reading{},
+	}
+}
+
+func (f *RateFilter) Name() string {
+	return "rate"
+}
+
+func (f *RateFilter) Apply(reading *Reading) (*Reading, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	last, ok := f.last[reading.SensorID]
+	if !ok {
+		f.last[reading.SensorID] = reading
+		return reading, nil
+	}
+	// a late reading, e.g. a buffered MQTT message, is not compared and must not become the reference of the
+	// following readings
+	if !reading.Timestamp.After(last.Timestamp) {
+		return reading, nil
+	}
+
+	minutes := math.Max(reading.Timestamp.Sub(last.Timestamp).Minutes(), 1)
+
+	tempDelta := math.Abs(reading.Temperature - last.Temperature)
+	if f.maxTemperatureRate > 0 && tempDelta > f.maxTemperatureRate*minutes {
+		return nil, &RejectError{Filter: f.Name(), Reason: fmt.Sprintf(
+			"temperature changed by %.1f°C in %.1f minutes (max %.1f°C/min)", tempDelta, minutes, f.maxTemperatureRate)}
+	}
+
+	humidityDelta := math.Abs(reading.Humidity - last.Humidity)
+	if f.maxHumidityRate > 0 && humidityDelta > f.maxHumidityRate*minutes {
+		return nil, &RejectError{Filter: f.Name(), Reason: fmt.Sprintf(
+			"humidity changed by %.1f%% in %.1f minutes (max %.1f%%/min)", humidityDelta, minutes, f.maxHumidityRate)}
+	}
+
+	f.last[reading.SensorID] = reading
+	return reading, nil
+}
+
+// MedianFilter replaces the values of a reading with the median of the last N readings of the sensor.
+// A size of 1 or less passes the readings unchanged.
+type MedianFilter struct {
+	size int
+
+	mu           sync.Mutex
+	temperatures map[int][]float64
+	humidities   map[int][]float64
+}
+
+func NewMedianFilter(size int) *MedianFilter {
+	return &MedianFilter{
+		size:         size,
+		temperatures: map[int][]float64{},
+		humidities:   map[int][]float64{},
+	}
+}
+
+func (f *MedianFilter) Name() string {
+	return "median"
+}
+
+func (f *MedianFilter) Apply(reading *Reading) (*Reading, error) {
+	if f.size <= 1 {
+		return reading, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.temperatures[reading.SensorID] = appendWindow(f.temperatures[reading.SensorID], reading.Temperature, f.size)
+	f.humidities[reading.SensorID] = appendWindow(f.humidities[reading.SensorID], reading.Humidity, f.size)
+
+	filtered := *reading
+	filtered.Temperature = median(f.temperatures[reading.SensorID])
+	filtered.Humidity = median(f.humidities[reading.SensorID])
+	return &filtered, nil
+}
+
+func appendWindow(window []float64, value float64, size int) []float64 {
+	window = append(window, value)
+	if len(window) > size {
+		window = window[len(window)-size:]
+	}
+	return window
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return math.Round((sorted[mid-1]+sorted[mid])/2*10) / 10
+	}
+	return sorted[mid]
+}
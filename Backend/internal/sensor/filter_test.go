@@ -0,0 +1,178 @@
+package sensor
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRangeFilter(t *testing.T) {
+	filter := NewRangeFilter(-40, 80, 0, 100)
+
+	tests := []struct {
+		name        string
+		temperature float64
+		humidity    float64
+		wantReject  bool
+	}{
+		{name: "valid", temperature: 21.5, humidity: 55},
+		{name: "at the limits", temperature: -40, humidity: 100},
+		{name: "too cold", temperature: -40.1, humidity: 55, wantReject: true},
+		{name: "too hot", temperature: 85, humidity: 55, wantReject: true},
+		{name: "negative humidity", temperature: 21.5, humidity: -1, wantReject: true},
+		{name: "humidity above 100%", temperature: 21.5, humidity: 100.1, wantReject: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := filter.Apply(&Reading{SensorID: 1, Temperature: tt.temperature, Humidity: tt.humidity})
+			assertRejected(t, err, tt.wantReject)
+		})
+	}
+}
+
+type rateStep struct {
+	sensorID    int
+	at          time.Duration
+	temperature float64
+	humidity    float64
+	wantReject  bool
+}
+
+func TestRateFilter(t *testing.T) {
+	start := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		// allowed change per minute, 0 disables the check
+		temperatureRate float64
+		humidityRate    float64
+		steps           []rateStep
+	}{
+		{name: "steady change", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: 5 * time.Minute, temperature: 21, humidity: 52},
+		}},
+		{name: "temperature jump", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: time.Minute, temperature: 25, humidity: 50, wantReject: true},
+		}},
+		{name: "humidity jump", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: 2 * time.Minute, temperature: 20, humidity: 60, wantReject: true},
+		}},
+		{name: "short interval counts as a minute", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: 10 * time.Second, temperature: 20.5, humidity: 51.5},
+		}},
+		{name: "rejected reading is not the reference", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: time.Minute, temperature: 30, humidity: 50, wantReject: true},
+			{at: 2 * time.Minute, temperature: 20.5, humidity: 50},
+		}},
+		{name: "late reading is not the reference", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: 10 * time.Minute, temperature: 22, humidity: 50},
+			{at: 5 * time.Minute, temperature: 30, humidity: 50},
+			{at: 11 * time.Minute, temperature: 22.4, humidity: 50},
+		}},
+		{name: "repeated reading", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: 0, temperature: 20, humidity: 50},
+			{at: time.Minute, temperature: 25, humidity: 50, wantReject: true},
+		}},
+		{name: "sensors are compared separately", temperatureRate: 0.5, humidityRate: 2, steps: []rateStep{
+			{sensorID: 1, at: 0, temperature: 20, humidity: 50},
+			{sensorID: 2, at: time.Minute, temperature: 5, humidity: 90},
+			{sensorID: 1, at: 2 * time.Minute, temperature: 20.5, humidity: 51},
+		}},
+		{name: "disabled", steps: []rateStep{
+			{at: 0, temperature: 20, humidity: 50},
+			{at: time.Minute, temperature: 40, humidity: 90},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewRateFilter(tt.temperatureRate, tt.humidityRate)
+			for i, step := range tt.steps {
+				_, err := filter.Apply(&Reading{SensorID: step.sensorID, Temperature: step.temperature,
+					Humidity: step.humidity, Timestamp: start.Add(step.at)})
+				if (err != nil) != step.wantReject {
+					t.Fatalf("step %d: Apply() error = %v, want rejected %v", i, err, step.wantReject)
+				}
+			}
+		})
+	}
+}
+
+func TestMedianFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		values []float64
+		want   []float64
+	}{
+		{name: "odd window", size: 3, values: []float64{20, 30, 21, 22}, want: []float64{20, 25, 21, 22}},
+		{name: "even window", size: 2, values: []float64{20, 21, 25}, want: []float64{20, 20.5, 23}},
+		{name: "spike is removed", size: 5, values: []float64{20, 20.2, 45, 20.2, 20.1},
+			want: []float64{20, 20.1, 20.2, 20.2, 20.2}},
+		{name: "disabled", size: 1, values: []float64{20, 45, 21}, want: []float64{20, 45, 21}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := NewMedianFilter(tt.size)
+			for i, value := range tt.values {
+				reading := &Reading{SensorID: 1, Temperature: value, Humidity: value + 30}
+				filtered, err := filter.Apply(reading)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantHumidity := tt.want[i] + 30
+				if math.Abs(filtered.Temperature-tt.want[i]) > 1e-9 || math.Abs(filtered.Humidity-wantHumidity) > 1e-9 {
+					t.Errorf("reading %d: %.1f°C and %.1f%%, want %.1f°C and %.1f%%", i, filtered.Temperature,
+						filtered.Humidity, tt.want[i], wantHumidity)
+				}
+				if reading.Temperature != value {
+					t.Errorf("reading %d: the original reading was changed", i)
+				}
+			}
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	median := NewMedianFilter(3)
+	pipeline := NewPipeline(NewRangeFilter(-40, 80, 0, 100), median)
+
+	if _, err := pipeline.Process(&Reading{SensorID: 1, Temperature: 20, Humidity: 50}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := pipeline.Process(&Reading{SensorID: 1, Temperature: 200, Humidity: 50})
+	var reject *RejectError
+	if !errors.As(err, &reject) || reject.Filter != "range" {
+		t.Fatalf("expected a rejection by the range filter, got %v", err)
+	}
+
+	// the rejected reading never reached the median filter
+	reading, err := pipeline.Process(&Reading{SensorID: 1, Temperature: 22, Humidity: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reading.Temperature != 21 {
+		t.Errorf("median of %.1f°C, want 21°C", reading.Temperature)
+	}
+}
+
+func assertRejected(t *testing.T, err error, wantReject bool) {
+	t.Helper()
+	var reject *RejectError
+	if wantReject && !errors.As(err, &reject) {
+		t.Errorf("expected a rejection, got %v", err)
+	}
+	if !wantReject && err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
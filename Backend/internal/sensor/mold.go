@@ -0,0 +1,64 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/climate"
+	"BeRoHuTe/internal/contracts"
+	"time"
+)
+
+const (
+	MoldRiskLow      = "low"
+	MoldRiskModerate = "moderate"
+	MoldRiskHigh     = "high"
+)
+
+// mold needs sustained dampness to germinate, so long streaks above the critical humidity weigh more than
+// the same number of scattered hours
+const (
+	moderateMoldScore  = 5
+	highMoldScore      = 25
+	moderateMoldStreak = 12
+	highMoldStreak     = 48
+)
+
+// AssessMoldRisk rates the mold risk of a sensor from its hourly average readings (ordered by time).
+// The score is the percentage of hours above the critical humidity for the measured temperature.
+func AssessMoldRisk(sensorID int, days int, hourly []*contracts.SensorReading) *contracts.MoldRisk {
+	risk := &contracts.MoldRisk{
+		SensorID: sensorID,
+		Days:     days,
+		Hours:    len(hourly),
+		Level:    MoldRiskLow,
+	}
+
+	streak := 0
+	var last time.Time
+	for _, reading := range hourly {
+		if reading.Humidity < climate.CriticalHumidity(reading.Temperature) {
+			streak = 0
+			continue
+		}
+
+		risk.CriticalHours++
+		// a missing hour breaks the streak, we do not know what happened in between
+		if streak > 0 && reading.Timestamp.Sub(last) > time.Hour {
+			streak = 0
+		}
+		streak++
+		last = reading.Timestamp
+		risk.LongestCriticalStreak = max(risk.LongestCriticalStreak, streak)
+	}
+
+	if risk.Hours > 0 {
+		risk.Score = climate.Round(float64(risk.CriticalHours)/float64(risk.Hours)*100, 1)
+	}
+
+	switch {
+	case risk.Score >= highMoldScore || risk.LongestCriticalStreak >= highMoldStreak:
+		risk.Level = MoldRiskHigh
+	case risk.Score >= moderateMoldScore || risk.LongestCriticalStreak >= moderateMoldStreak:
+		risk.Level = MoldRiskModerate
+	}
+
+	return risk
+}
@@ -0,0 +1,174 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hourlyReadings returns a reading per hour of the pattern, 'd' for a damp hour above the critical humidity,
+// 'n' for a normal hour and '-' for an hour without readings
+func hourlyReadings(start time.Time, pattern string) []*contracts.SensorReading {
+	var readings []*contracts.SensorReading
+	for i, c := range pattern {
+		humidity := 50.0
+		switch c {
+		case '-':
+			continue
+		case 'd':
+			humidity = 85
+		}
+		readings = append(readings, &contracts.SensorReading{SensorID: 1, Temperature: 21, Humidity: humidity,
+			Timestamp: start.Add(time.Duration(i) * time.Hour)})
+	}
+	return readings
+}
+
+func TestAssessMoldRisk(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	normal := func(hours int) string { return strings.Repeat("n", hours) }
+	damp := func(hours int) string { return strings.Repeat("d", hours) }
+
+	tests := []struct {
+		name         string
+		pattern      string
+		wantCritical int
+		wantStreak   int
+		wantScore    float64
+		wantLevel    string
+	}{
+		{name: "no readings", wantLevel: MoldRiskLow},
+		{name: "dry room", pattern: normal(168), wantLevel: MoldRiskLow},
+		{name: "few damp hours", pattern: normal(96) + "dnndnnd" + normal(97), wantCritical: 3, wantStreak: 1,
+			wantScore: 1.5, wantLevel: MoldRiskLow},
+		{name: "scattered damp hours", pattern: strings.Repeat("dnnnnnnnnnnnnnnnnnnn", 5), wantCritical: 5,
+			wantStreak: 1, wantScore: 5, wantLevel: MoldRiskModerate},
+		{name: "long damp streak", pattern: normal(150) + damp(12) + normal(138), wantCritical: 12,
+			wantStreak: 12, wantScore: 4, wantLevel: MoldRiskModerate},
+		{name: "missing hour breaks the streak", pattern: normal(150) + damp(6) + "-" + damp(6) + normal(137),
+			wantCritical: 12, wantStreak: 6, wantScore: 4, wantLevel: MoldRiskLow},
+		{name: "damp most of the time", pattern: strings.Repeat("ddn", 50), wantCritical: 100, wantStreak: 2,
+			wantScore: 66.7, wantLevel: MoldRiskHigh},
+		{name: "two damp days", pattern: normal(500) + damp(48) + normal(452), wantCritical: 48, wantStreak: 48,
+			wantScore: 4.8, wantLevel: MoldRiskHigh},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hourly := hourlyReadings(start, tt.pattern)
+			risk := AssessMoldRisk(1, 7, hourly)
+
+			if risk.Hours != len(hourly) || risk.CriticalHours != tt.wantCritical {
+				t.Errorf("%d critical of %d hours, want %d of %d", risk.CriticalHours, risk.Hours, tt.wantCritical,
+					len(hourly))
+			}
+			if risk.LongestCriticalStreak != tt.wantStreak {
+				t.Errorf("longest streak of %d hours, want %d", risk.LongestCriticalStreak, tt.wantStreak)
+			}
+			if risk.Score != tt.wantScore {
+				t.Errorf("score %.1f, want %.1f", risk.Score, tt.wantScore)
+			}
+			if risk.Level != tt.wantLevel {
+				t.Errorf("level %s, want %s", risk.Level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+// the critical humidity rises in the cold, 85% is critical in a heated room but not at 5°C
+func TestAssessMoldRiskDependsOnTemperature(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)
+	hourly := hourlyReadings(start, strings.Repeat("d", 24))
+	for _, reading := range hourly {
+		reading.Temperature = 5
+	}
+
+	if risk := AssessMoldRisk(1, 7, hourly); risk.CriticalHours != 0 || risk.Level != MoldRiskLow {
+		t.Errorf("%d critical hours at level %s, want none at 5°C", risk.CriticalHours, risk.Level)
+	}
+}
+
+func TestGetHourlyAverages(t *testing.T) {
+	repo, err := New(newTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hour := time.Now().Add(-24 * time.Hour).Truncate(time.Hour)
+	for _, reading := range []contracts.SensorReading{
+		{SensorID: 1, Temperature: 20, Humidity: 60, Timestamp: hour.Add(10 * time.Minute)},
+		{SensorID: 1, Temperature: 22, Humidity: 70, Timestamp: hour.Add(40 * time.Minute)},
+		{SensorID: 1, Temperature: 21, Humidity: 50, Timestamp: hour.Add(70 * time.Minute)},
+		// outside of the period
+		{SensorID: 1, Temperature: 30, Humidity: 90, Timestamp: hour.Add(-8 * 24 * time.Hour)},
+	} {
+		if err := repo.Save(reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hourly, err := repo.GetHourlyAverages(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readings := hourly[1]
+	if len(readings) != 2 {
+		t.Fatalf("%d hourly averages, want 2", len(readings))
+	}
+	if readings[0].Temperature != 21 || readings[0].Humidity != 65 {
+		t.Errorf("first hour averages %.1f°C and %.1f%%, want 21°C and 65%%", readings[0].Temperature,
+			readings[0].Humidity)
+	}
+	// the hours are read in the local time zone they were stored in
+	if !readings[0].Timestamp.Equal(hour) || !readings[1].Timestamp.Equal(hour.Add(time.Hour)) {
+		t.Errorf("hours %v and %v, want %v and %v", readings[0].Timestamp, readings[1].Timestamp, hour,
+			hour.Add(time.Hour))
+	}
+}
+
+func TestGetMoldRiskIsCachedPerHour(t *testing.T) {
+	repo, err := New(newTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-48 * time.Hour).Truncate(time.Hour)
+	for _, reading := range hourlyReadings(start, strings.Repeat("n", 20)) {
+		if err := repo.Save(*reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hour := time.Now().Truncate(time.Hour)
+	risks, err := repo.GetMoldRisk(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(risks) != 1 || risks[0].CriticalHours != 0 {
+		t.Fatalf("risks %+v, want sensor 1 without critical hours", risks)
+	}
+
+	for _, reading := range hourlyReadings(start.Add(20*time.Hour), strings.Repeat("d", 20)) {
+		if err := repo.Save(*reading); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cached, err := repo.GetMoldRisk(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Now().Truncate(time.Hour).Equal(hour) && cached[0].CriticalHours != 0 {
+		t.Errorf("assessed again within the hour, %d critical hours", cached[0].CriticalHours)
+	}
+
+	// the next hour started
+	entry := repo.moldRisks[7]
+	entry.hour = entry.hour.Add(-time.Hour)
+	repo.moldRisks[7] = entry
+	risks, err = repo.GetMoldRisk(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if risks[0].CriticalHours != 20 {
+		t.Errorf("%d critical hours, want the 20 new ones", risks[0].CriticalHours)
+	}
+}
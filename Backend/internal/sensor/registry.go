@@ -0,0 +1,211 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"BeRoHuTe/util"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sources a sensor can be read from
+const (
+	SourceRedis = "redis"
+	SourceMQTT  = "mqtt"
+	SourcePush  = "push"
+)
+
+const upsertSensorQuery = `INSERT INTO sensors (id, name, room, redis_key, source, enabled) VALUES (?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET name = excluded.name, room = excluded.room,
+		redis_key = excluded.redis_key, source = excluded.source, enabled = excluded.enabled`
+
+const selectSensorQuery = `SELECT id, name, room, redis_key, source, enabled FROM sensors`
+
+// Registry holds the configured sensors, persisted next to the readings table
+type Registry struct {
+	db *sql.DB
+}
+
+// NewRegistry creates a new sensor registry and seeds it with the two default sensors if it is empty
+func NewRegistry(db *sql.DB) (*Registry, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	registry := &Registry{db: db}
+	if err := registry.createTable(); err != nil {
+		return nil, err
+	}
+	if err := registry.seed(); err != nil {
+		return nil, err
+	}
+
+	return registry, nil
+}
+
+func (r *Registry) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS sensors (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		room TEXT NOT NULL DEFAULT '',
+		redis_key TEXT NOT NULL,
+		enabled INTEGER NOT NULL DEFAULT 1
+	)`
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+
+	return util.AddColumnIfMissing(r.db, "sensors", "source", "TEXT NOT NULL DEFAULT '"+SourceRedis+"'")
+}
+
+// seed inserts the sensors written by dht22.py, so existing setups keep working without configuration
+func (r *Registry) seed() error {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM sensors`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, id := range []int{1, 2} {
+		err := r.Save(&contracts.Sensor{
+			ID:       id,
+			Name:     fmt.Sprintf("Sensor %d", id),
+			RedisKey: fmt.Sprintf("sensor%d", id),
+			Source:   SourceRedis,
+			Enabled:  true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save inserts a sensor or updates it if the ID already exists
+func (r *Registry) Save(sensor *contracts.Sensor) error {
+	_, err := r.db.Exec(upsertSensorQuery, sensor.ID, sensor.Name, sensor.Room, sensor.RedisKey, sensor.Source, sensor.Enabled)
+	return err
+}
+
+// Sync stores the given sensors and disables all registered sensors missing in the list
+func (r *Registry) Sync(sensors []*contracts.Sensor) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE sensors SET enabled = 0`); err != nil {
+		return err
+	}
+	for _, sensor := range sensors {
+		if _, err := tx.Exec(upsertSensorQuery, sensor.ID, sensor.Name, sensor.Room, sensor.RedisKey, sensor.Source, sensor.Enabled); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the sensor with the given ID
+func (r *Registry) Get(sensorID int) (*contracts.Sensor, error) {
+	query := selectSensorQuery + ` WHERE id = ?`
+	sensors, err := r.querySensors(query, sensorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sensors) == 0 {
+		return nil, fmt.Errorf("unknown sensor ID: %d", sensorID)
+	}
+	return sensors[0], nil
+}
+
+// GetAll returns all registered sensors
+func (r *Registry) GetAll() ([]*contracts.Sensor, error) {
+	query := selectSensorQuery + ` ORDER BY id`
+	return r.querySensors(query)
+}
+
+// GetEnabled returns all sensors which should be read
+func (r *Registry) GetEnabled() ([]*contracts.Sensor, error) {
+	query := selectSensorQuery + ` WHERE enabled = 1 ORDER BY id`
+	return r.querySensors(query)
+}
+
+// GetEnabledBySource returns all sensors which should be read from the given source
+func (r *Registry) GetEnabledBySource(source string) ([]*contracts.Sensor, error) {
+	query := selectSensorQuery + ` WHERE enabled = 1 AND source = ? ORDER BY id`
+	return r.querySensors(query, source)
+}
+
+func (r *Registry) querySensors(query string, args ...interface{}) ([]*contracts.Sensor, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sensors []*contracts.Sensor
+	for rows.Next() {
+		var sensor contracts.Sensor
+		err := rows.Scan(&sensor.ID, &sensor.Name, &sensor.Room, &sensor.RedisKey, &sensor.Source, &sensor.Enabled)
+		if err != nil {
+			return nil, err
+		}
+		sensors = append(sensors, &sensor)
+	}
+
+	return sensors, rows.Err()
+}
+
+// ParseSensors parses a sensor list in the format "id:name:room:redis_key:source,..."
+// room, redis_key and source are optional, the redis key defaults to "sensor<id>" and the source to "redis"
+func ParseSensors(value string) ([]*contracts.Sensor, error) {
+	var sensors []*contracts.Sensor
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 || len(fields) > 5 {
+			return nil, fmt.Errorf("invalid sensor definition %q, expected id:name[:room[:redis_key[:source]]]", entry)
+		}
+
+		id, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensor ID in %q: %v", entry, err)
+		}
+
+		sensor := &contracts.Sensor{
+			ID:       id,
+			Name:     strings.TrimSpace(fields[1]),
+			RedisKey: fmt.Sprintf("sensor%d", id),
+			Source:   SourceRedis,
+			Enabled:  true,
+		}
+		if len(fields) > 2 {
+			sensor.Room = strings.TrimSpace(fields[2])
+		}
+		if len(fields) > 3 && strings.TrimSpace(fields[3]) != "" {
+			sensor.RedisKey = strings.TrimSpace(fields[3])
+		}
+		if len(fields) > 4 && strings.TrimSpace(fields[4]) != "" {
+			sensor.Source = strings.TrimSpace(fields[4])
+		}
+		if sensor.Name == "" {
+			return nil, errors.New("sensor name must not be empty")
+		}
+
+		sensors = append(sensors, sensor)
+	}
+
+	return sensors, nil
+}
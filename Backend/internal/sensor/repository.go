@@ -1,13 +1,27 @@
 package sensor
 
 import (
+	"BeRoHuTe/internal/climate"
 	"BeRoHuTe/internal/contracts"
+	"BeRoHuTe/util"
 	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 )
 
 type Repository struct {
 	db *sql.DB
+
+	moldRiskMu sync.Mutex
+	moldRisks  map[int]moldRiskEntry
+}
+
+// moldRiskEntry is the mold risk over a number of days, assessed in the given hour
+type moldRiskEntry struct {
+	hour  time.Time
+	risks []*contracts.MoldRisk
 }
 
 // New creates a new repository and initializes the database
@@ -16,7 +30,7 @@ func New(db *sql.DB) (*Repository, error) {
 		return nil, err
 	}
 
-	repo := &Repository{db: db}
+	repo := &Repository{db: db, moldRisks: make(map[int]moldRiskEntry)}
 	if err := repo.createTable(); err != nil {
 		return nil, err
 	}
@@ -34,13 +48,37 @@ func (r *Repository) createTable() error {
 		timestamp DATETIME NOT NULL
 	);
 	CREATE INDEX IF NOT EXISTS idx_sensor_timestamp ON readings(sensor_id, timestamp);
+	CREATE TABLE IF NOT EXISTS quarantined_readings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		sensor_id INTEGER NOT NULL,
+		temperature REAL NOT NULL,
+		humidity REAL NOT NULL,
+		raw_temperature REAL NOT NULL,
+		raw_humidity REAL NOT NULL,
+		timestamp DATETIME NOT NULL,
+		filter TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
 	`
-	_, err := r.db.Exec(query)
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+
+	// raw values before calibration, readings stored before calibration support are raw already
+	if err := util.AddColumnIfMissing(r.db, "readings", "raw_temperature", "REAL"); err != nil {
+		return err
+	}
+	if err := util.AddColumnIfMissing(r.db, "readings", "raw_humidity", "REAL"); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`UPDATE readings SET raw_temperature = temperature, raw_humidity = humidity 
+		WHERE raw_temperature IS NULL OR raw_humidity IS NULL`)
 	return err
 }
 
 func (r *Repository) GetInBetween(start time.Time, end time.Time) ([]*contracts.SensorReading, error) {
-	query := `SELECT id, sensor_id, temperature, humidity, timestamp FROM readings 
+	query := `SELECT id, sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp FROM readings 
 	WHERE timestamp >= ? AND timestamp <= ?`
 	return r.queryReadings(query, start, end)
 }
@@ -51,17 +89,67 @@ func (r *Repository) Delete(id int64) error {
 	return err
 }
 
-// Save stores a new reading
-func (r *Repository) Save(sensorID int, temperature, humidity float64, timestamp time.Time) error {
-	query := `INSERT INTO readings (sensor_id, temperature, humidity, timestamp) VALUES (?, ?, ?, ?)`
-	_, err := r.db.Exec(query, sensorID, temperature, humidity, timestamp)
+// Save stores a new reading, the raw values are the ones before calibration
+func (r *Repository) Save(reading contracts.SensorReading) error {
+	query := `INSERT INTO readings (sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp) 
+	VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, reading.SensorID, reading.Temperature, reading.Humidity,
+		reading.RawTemperature, reading.RawHumidity, reading.Timestamp)
 	return err
 }
 
+// Quarantine stores a reading rejected by the filter pipeline
+func (r *Repository) Quarantine(reading contracts.QuarantinedReading) error {
+	query := `INSERT INTO quarantined_readings 
+	(sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp, filter, reason, created_at) 
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, reading.SensorID, reading.Temperature, reading.Humidity,
+		reading.RawTemperature, reading.RawHumidity, reading.Timestamp, reading.Filter, reading.Reason, reading.CreatedAt)
+	return err
+}
+
+// GetQuarantined returns the last N rejected readings
+func (r *Repository) GetQuarantined(n int) ([]*contracts.QuarantinedReading, error) {
+	query := `SELECT id, sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp, filter, reason, created_at
+	FROM quarantined_readings ORDER BY timestamp DESC LIMIT ?`
+	rows, err := r.db.Query(query, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var readings []*contracts.QuarantinedReading
+	for rows.Next() {
+		var reading contracts.QuarantinedReading
+		err := rows.Scan(&reading.ID, &reading.SensorID, &reading.Temperature, &reading.Humidity,
+			&reading.RawTemperature, &reading.RawHumidity, &reading.Timestamp, &reading.Filter, &reading.Reason,
+			&reading.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		readings = append(readings, &reading)
+	}
+
+	return readings, rows.Err()
+}
+
+// Recalibrate recomputes the calibrated values from the raw values for all readings of a sensor in [from, to)
+func (r *Repository) Recalibrate(cal contracts.SensorCalibration, from time.Time, to time.Time) (int64, error) {
+	query := `UPDATE readings 
+	SET temperature = ROUND(raw_temperature * ? + ?, 1), humidity = ROUND(MIN(MAX(raw_humidity * ? + ?, 0), 100), 1)
+	WHERE sensor_id = ? AND timestamp >= ? AND timestamp < ?`
+	res, err := r.db.Exec(query, cal.TemperatureGain, cal.TemperatureOffset, cal.HumidityGain, cal.HumidityOffset,
+		cal.SensorID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
 // GetLatest returns the latest reading for each sensor
 func (r *Repository) GetLatest() ([]*contracts.SensorReading, error) {
 	query := `
-	SELECT id, sensor_id, temperature, humidity, timestamp
+	SELECT id, sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp
 	FROM readings
 	WHERE (sensor_id, timestamp) IN (
 		SELECT sensor_id, MAX(timestamp)
@@ -76,7 +164,7 @@ func (r *Repository) GetLatest() ([]*contracts.SensorReading, error) {
 // GetLastN returns the last N readings for all sensors
 func (r *Repository) GetLastN(n int) ([]*contracts.SensorReading, error) {
 	query := `
-	SELECT id, sensor_id, temperature, humidity, timestamp
+	SELECT id, sensor_id, temperature, humidity, raw_temperature, raw_humidity, timestamp
 	FROM readings
 	ORDER BY timestamp DESC
 	LIMIT ?
@@ -84,39 +172,97 @@ func (r *Repository) GetLastN(n int) ([]*contracts.SensorReading, error) {
 	return r.queryReadings(query, n)
 }
 
-// GetAverageLastHour returns average temperature and humidity for each sensor in the last hour
+// GetAverageLastHour returns average temperature, humidity and derived metrics for each sensor in the last hour
 func (r *Repository) GetAverageLastHour() (map[int]map[string]float64, error) {
 	query := `
-	SELECT sensor_id, AVG(temperature) as avg_temp, AVG(humidity) as avg_humidity
+	SELECT sensor_id, temperature, humidity
 	FROM readings
 	WHERE timestamp >= datetime('now', '-1 hour')
-	GROUP BY sensor_id
 	`
 	return r.queryAverages(query)
 }
 
-// GetAverageToday returns average temperature and humidity for each sensor today
+// GetAverageToday returns average temperature, humidity and derived metrics for each sensor today
 func (r *Repository) GetAverageToday() (map[int]map[string]float64, error) {
 	query := `
-	SELECT sensor_id, AVG(temperature) as avg_temp, AVG(humidity) as avg_humidity
+	SELECT sensor_id, temperature, humidity
 	FROM readings
 	WHERE timestamp > date('now') AND timestamp <= date('now', '+1 day')
-	GROUP BY sensor_id
 	`
 	return r.queryAverages(query)
 }
 
-// GetAverageThisWeek returns average temperature and humidity for each sensor this week
+// GetAverageThisWeek returns average temperature, humidity and derived metrics for each sensor this week
 func (r *Repository) GetAverageThisWeek() (map[int]map[string]float64, error) {
 	query := `
-	SELECT sensor_id, AVG(temperature) as avg_temp, AVG(humidity) as avg_humidity
+	SELECT sensor_id, temperature, humidity
 	FROM readings
 	WHERE timestamp >= datetime('now', '-7 days')
-	GROUP BY sensor_id
 	`
 	return r.queryAverages(query)
 }
 
+// GetHourlyAverages returns the hourly average readings of the last days for each sensor, ordered by time
+func (r *Repository) GetHourlyAverages(days int) (map[int][]*contracts.SensorReading, error) {
+	// timestamps are stored as text in the local time zone, the first 13 characters are the date and hour
+	query := `
+	SELECT sensor_id, substr(timestamp, 1, 13) as hour, AVG(temperature), AVG(humidity)
+	FROM readings
+	WHERE timestamp >= datetime('now', ?, 'localtime')
+	GROUP BY sensor_id, hour
+	ORDER BY sensor_id, hour
+	`
+	rows, err := r.db.Query(query, fmt.Sprintf("-%d days", days))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[int][]*contracts.SensorReading)
+	for rows.Next() {
+		var reading contracts.SensorReading
+		var hour string
+		if err := rows.Scan(&reading.SensorID, &hour, &reading.Temperature, &reading.Humidity); err != nil {
+			return nil, err
+		}
+		reading.Timestamp, err = time.ParseInLocation("2006-01-02 15", hour, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		result[reading.SensorID] = append(result[reading.SensorID], &reading)
+	}
+
+	return result, rows.Err()
+}
+
+// GetMoldRisk returns the mold risk of each sensor over the last days. It counts whole hours, so it is assessed
+// once per hour and cached, the dashboard requests it on every load.
+func (r *Repository) GetMoldRisk(days int) ([]*contracts.MoldRisk, error) {
+	r.moldRiskMu.Lock()
+	defer r.moldRiskMu.Unlock()
+
+	hour := time.Now().Truncate(time.Hour)
+	if entry, ok := r.moldRisks[days]; ok && entry.hour.Equal(hour) {
+		return entry.risks, nil
+	}
+
+	hourly, err := r.GetHourlyAverages(days)
+	if err != nil {
+		return nil, err
+	}
+
+	risks := make([]*contracts.MoldRisk, 0, len(hourly))
+	for sensorID, readings := range hourly {
+		risks = append(risks, AssessMoldRisk(sensorID, days, readings))
+	}
+	sort.Slice(risks, func(i, j int) bool {
+		return risks[i].SensorID < risks[j].SensorID
+	})
+
+	r.moldRisks[days] = moldRiskEntry{hour: hour, risks: risks}
+	return risks, nil
+}
+
 func (r *Repository) queryReadings(query string, args ...interface{}) ([]*contracts.SensorReading, error) {
 	rows, err := r.db.Query(query, args...)
 	if err != nil {
@@ -127,10 +273,14 @@ func (r *Repository) queryReadings(query string, args ...interface{}) ([]*contra
 	var readings []*contracts.SensorReading
 	for rows.Next() {
 		var reading contracts.SensorReading
-		err := rows.Scan(&reading.ID, &reading.SensorID, &reading.Temperature, &reading.Humidity, &reading.Timestamp)
+		err := rows.Scan(&reading.ID, &reading.SensorID, &reading.Temperature, &reading.Humidity,
+			&reading.RawTemperature, &reading.RawHumidity, &reading.Timestamp)
 		if err != nil {
 			return nil, err
 		}
+		reading.DewPoint = climate.Round(climate.DewPoint(reading.Temperature, reading.Humidity), 1)
+		reading.AbsoluteHumidity = climate.Round(climate.AbsoluteHumidity(reading.Temperature, reading.Humidity), 2)
+		reading.HeatIndex = climate.Round(climate.HeatIndex(reading.Temperature, reading.Humidity), 1)
 		readings = append(readings, &reading)
 	}
 
@@ -144,19 +294,25 @@ func (r *Repository) queryAverages(query string) (map[int]map[string]float64, er
 	}
 	defer rows.Close()
 
-	result := make(map[int]map[string]float64)
+	averages := make(map[int]*climate.Average)
 	for rows.Next() {
 		var sensorID int
-		var avgTemp, avgHumidity float64
-		err := rows.Scan(&sensorID, &avgTemp, &avgHumidity)
-		if err != nil {
+		var temperature, humidity float64
+		if err := rows.Scan(&sensorID, &temperature, &humidity); err != nil {
 			return nil, err
 		}
-		result[sensorID] = map[string]float64{
-			"temperature": avgTemp,
-			"humidity":    avgHumidity,
+		if averages[sensorID] == nil {
+			averages[sensorID] = &climate.Average{}
 		}
+		averages[sensorID].Add(temperature, humidity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return result, rows.Err()
+	result := make(map[int]map[string]float64, len(averages))
+	for sensorID, average := range averages {
+		result[sensorID] = average.Metrics().Map()
+	}
+	return result, nil
 }
@@ -1,8 +1,10 @@
 package sensor
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"math/rand/v2"
 	"time"
 )
 
@@ -12,49 +14,86 @@ type Reading struct {
 	Temperature float64   `json:"temperature"`
 	Humidity    float64   `json:"humidity"`
 	Timestamp   time.Time `json:"timestamp"`
+	// MessageID identifies the reading in its source if it has to be acknowledged
+	MessageID string `json:"-"`
+}
+
+// Result is the outcome of reading a single sensor, either Reading or Err is set
+type Result struct {
+	SensorID int
+	Reading  *Reading
+	Err      error
 }
 
 // Service defines the interface for reading sensor data
 type Service interface {
-	// ReadSensor reads data from a specific sensor (1 or 2)
+	// ReadSensor reads data from a specific registered sensor
 	ReadSensor(sensorID int) (*Reading, error)
-	// ReadAllSensors reads data from all sensors
-	ReadAllSensors() ([]*Reading, error)
+	// ReadAllSensors reads data from all enabled sensors of the registry, a failing sensor is reported in its
+	// Result and does not affect the others. The error is only set if the sensors could not be read at all,
+	// e.g. because the registry or the source is unavailable.
+	ReadAllSensors() ([]*Result, error)
+}
+
+// Acknowledger is implemented by services which have to be told that readings were processed
+type Acknowledger interface {
+	Acknowledge(readings []*Reading) error
 }
 
 // DummyService simulates sensor readings with values that change over time
 type DummyService struct {
-	startTime time.Time
+	startTime   time.Time
+	registry    *Registry
+	failureRate float64
+}
+
+type DummyOption func(*DummyService) error
+
+// WithFailureRate lets the given share of the readings fail like the checksum errors of a DHT22 (default 0)
+func WithFailureRate(rate float64) DummyOption {
+	return func(d *DummyService) error {
+		if rate < 0 || rate > 1 {
+			return errors.New("failure rate must be between 0 and 1")
+		}
+		d.failureRate = rate
+		return nil
+	}
 }
 
 // NewDummyService creates a new dummy sensor service
-func NewDummyService() *DummyService {
-	return &DummyService{
+func NewDummyService(registry *Registry, options ...DummyOption) (*DummyService, error) {
+	d := &DummyService{
 		startTime: time.Now(),
+		registry:  registry,
 	}
+	for _, option := range options {
+		if err := option(d); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
 }
 
 // ReadSensor reads simulated data from a specific sensor
 func (d *DummyService) ReadSensor(sensorID int) (*Reading, error) {
-	if sensorID < 1 || sensorID > 2 {
+	if _, err := d.registry.Get(sensorID); err != nil {
 		return nil, fmt.Errorf("invalid sensor ID: %d", sensorID)
 	}
 
+	if rand.Float64() < d.failureRate {
+		return nil, fmt.Errorf("simulated checksum error on sensor %d", sensorID)
+	}
+
 	now := time.Now()
 	elapsed := now.Sub(d.startTime).Seconds()
 
 	// Generate temperature and humidity based on sine waves for variation
 	// Sensor 1: 20-24°C, 45-55% humidity
 	// Sensor 2: 19-23°C, 50-60% humidity
-	var temp, humidity float64
-
-	if sensorID == 1 {
-		temp = 22.0 + 2.0*math.Sin(elapsed/10.0)
-		humidity = 50.0 + 5.0*math.Cos(elapsed/15.0)
-	} else {
-		temp = 21.0 + 2.0*math.Sin(elapsed/12.0)
-		humidity = 55.0 + 5.0*math.Cos(elapsed/18.0)
-	}
+	// every further sensor is a bit colder and more humid
+	id := float64(sensorID)
+	temp := 23.0 - id + 2.0*math.Sin(elapsed/(8.0+2.0*id))
+	humidity := 45.0 + 5.0*id + 5.0*math.Cos(elapsed/(12.0+3.0*id))
 
 	return &Reading{
 		SensorID:    sensorID,
@@ -64,17 +103,18 @@ func (d *DummyService) ReadSensor(sensorID int) (*Reading, error) {
 	}, nil
 }
 
-// ReadAllSensors reads simulated data from all sensors
-func (d *DummyService) ReadAllSensors() ([]*Reading, error) {
-	readings := make([]*Reading, 0, 2)
+// ReadAllSensors reads simulated data from all enabled sensors read via redis on the raspberry pi
+func (d *DummyService) ReadAllSensors() ([]*Result, error) {
+	sensors, err := d.registry.GetEnabledBySource(SourceRedis)
+	if err != nil {
+		return nil, err
+	}
 
-	for i := 1; i <= 2; i++ {
-		reading, err := d.ReadSensor(i)
-		if err != nil {
-			return nil, err
-		}
-		readings = append(readings, reading)
+	results := make([]*Result, 0, len(sensors))
+	for _, sensor := range sensors {
+		reading, err := d.ReadSensor(sensor.ID)
+		results = append(results, &Result{SensorID: sensor.ID, Reading: reading, Err: err})
 	}
 
-	return readings, nil
+	return results, nil
 }
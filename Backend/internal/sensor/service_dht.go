@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"math"
 	"time"
 )
 
@@ -15,25 +16,31 @@ type sensorReading struct {
 	Timestamp    float64 `json:"timestamp"`
 }
 
-var allSensorIDs = []int{1, 2}
-
 type DHTSensors struct {
-	rdb *redis.Client
+	rdb      *redis.Client
+	registry *Registry
 }
 
-func NewDHTSensors(re *redis.Client) *DHTSensors {
-	return &DHTSensors{rdb: re}
+func NewDHTSensors(re *redis.Client, registry *Registry) *DHTSensors {
+	return &DHTSensors{rdb: re, registry: registry}
 }
 
 func (D DHTSensors) ReadSensor(sensorID int) (*Reading, error) {
-	// read from redis 'sensor<id>'
-	res, err := D.rdb.Get(context.Background(), fmt.
-		Sprintf("sensor%d", sensorID)).
-		Result()
+	sensor, err := D.registry.Get(sensorID)
 	if err != nil {
 		return nil, err
 	}
 
+	return D.readKey(sensor.ID, sensor.RedisKey)
+}
+
+func (D DHTSensors) readKey(sensorID int, key string) (*Reading, error) {
+	// read from redis, the key is configured in the registry (e.g. 'sensor<id>')
+	res, err := D.rdb.Get(context.Background(), key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read redis key %q: %w", key, err)
+	}
+
 	var reading sensorReading
 
 	if err := json.Unmarshal([]byte(res), &reading); err != nil {
@@ -44,22 +51,31 @@ func (D DHTSensors) ReadSensor(sensorID int) (*Reading, error) {
 		SensorID:    sensorID,
 		Temperature: reading.TemperatureC,
 		Humidity:    reading.Humidity,
-		Timestamp:   time.Unix(int64(reading.Timestamp), 0),
+		Timestamp:   unixTime(reading.Timestamp),
 	}
 
 	return &r, nil
 }
 
-func (D DHTSensors) ReadAllSensors() ([]*Reading, error) {
-	res := make([]*Reading, 0, len(allSensorIDs))
+func (D DHTSensors) ReadAllSensors() ([]*Result, error) {
+	sensors, err := D.registry.GetEnabledBySource(SourceRedis)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sensorID := range allSensorIDs {
-		reading, err := D.ReadSensor(sensorID)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, reading)
+	res := make([]*Result, 0, len(sensors))
+
+	for _, sensor := range sensors {
+		reading, err := D.readKey(sensor.ID, sensor.RedisKey)
+		res = append(res, &Result{SensorID: sensor.ID, Reading: reading, Err: err})
 	}
 
 	return res, nil
 }
+
+// unixTime converts the fractional Unix timestamp of dht22.py, keeping the sub-second part so two readings taken
+// in the same second are not mistaken for duplicates
+func unixTime(timestamp float64) time.Time {
+	seconds := math.Floor(timestamp)
+	return time.Unix(int64(seconds), int64((timestamp-seconds)*1e9))
+}
@@ -0,0 +1,348 @@
+package sensor
+
+import (
+	"encoding/json"
+	"fmt"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxBufferedResults limits the memory used if the readings are not consumed
+const maxBufferedResults = 10000
+
+type MQTTConfig struct {
+	// Broker is the broker URL, e.g. tcp://localhost:1883
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// Topics maps a topic to the ID of a registered sensor, a topic may be a filter with the wildcards + and #
+	Topics map[string]int
+	// the JSON fields of the payload, nested fields are separated by dots (e.g. "sensor.temp")
+	TemperatureField string
+	HumidityField    string
+	// TimestampField is optional, without it the time the message was received is used
+	TimestampField string
+}
+
+// MQTTSensors subscribes to the configured topics and buffers every received reading until it is read
+type MQTTSensors struct {
+	client   mqtt.Client
+	registry *Registry
+	config   MQTTConfig
+	// filters are the topics with wildcards, ordered from the most specific
+	filters []string
+
+	mu      sync.Mutex
+	results []*Result
+	latest  map[int]*Reading
+}
+
+func NewMQTTSensors(registry *Registry, config MQTTConfig) (*MQTTSensors, error) {
+	if config.TemperatureField == "" {
+		config.TemperatureField = "temperature"
+	}
+	if config.HumidityField == "" {
+		config.HumidityField = "humidity"
+	}
+	for topic := range config.Topics {
+		if err := validateTopicFilter(topic); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &MQTTSensors{
+		registry: registry,
+		config:   config,
+		filters:  wildcardFilters(config.Topics),
+		results:  make([]*Result, 0),
+		latest:   map[int]*Reading{},
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		// a single handler for all topics, a message matching several filters would be passed to each of them
+		SetDefaultPublishHandler(s.onMessage).
+		SetOnConnectHandler(s.subscribe) // subscribe again after every reconnect
+
+	s.client = mqtt.NewClient(opts)
+	token := s.client.Connect()
+	if !token.WaitTimeout(10 * time.Second) {
+		log.Printf("MQTT broker %s not reachable yet, retrying in the background", config.Broker)
+	} else if err := token.Error(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *MQTTSensors) subscribe(client mqtt.Client) {
+	filters := make(map[string]byte, len(s.config.Topics))
+	for topic := range s.config.Topics {
+		filters[topic] = 1
+	}
+
+	token := client.SubscribeMultiple(filters, nil)
+	if token.Wait() && token.Error() != nil {
+		log.Printf("Error subscribing to MQTT topics: %v", token.Error())
+	}
+}
+
+func (s *MQTTSensors) onMessage(_ mqtt.Client, message mqtt.Message) {
+	sensorID, ok := s.sensorForTopic(message.Topic())
+	if !ok {
+		return
+	}
+
+	reading, err := ParseMQTTPayload(message.Payload(), s.config)
+	if reading != nil {
+		reading.SensorID = sensorID
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid payload on topic %q: %w", message.Topic(), err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = append(s.results, &Result{SensorID: sensorID, Reading: reading, Err: err})
+	if len(s.results) > maxBufferedResults {
+		s.results = s.results[len(s.results)-maxBufferedResults:]
+	}
+	if err == nil {
+		s.latest[sensorID] = reading
+	}
+}
+
+// sensorForTopic returns the sensor of a topic, a topic matching several filters belongs to the most specific one
+func (s *MQTTSensors) sensorForTopic(topic string) (int, bool) {
+	if sensorID, ok := s.config.Topics[topic]; ok {
+		return sensorID, true
+	}
+	for _, filter := range s.filters {
+		if topicMatches(filter, topic) {
+			return s.config.Topics[filter], true
+		}
+	}
+	return 0, false
+}
+
+// ReadSensor returns the last reading received for the sensor
+func (s *MQTTSensors) ReadSensor(sensorID int) (*Reading, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reading, ok := s.latest[sensorID]
+	if !ok {
+		return nil, fmt.Errorf("no MQTT message received for sensor %d yet", sensorID)
+	}
+	return reading, nil
+}
+
+// ReadAllSensors returns all readings received since the last call for enabled MQTT sensors
+func (s *MQTTSensors) ReadAllSensors() ([]*Result, error) {
+	sensors, err := s.registry.GetEnabledBySource(SourceMQTT)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[int]bool, len(sensors))
+	for _, sensor := range sensors {
+		enabled[sensor.ID] = true
+	}
+
+	s.mu.Lock()
+	received := s.results
+	s.results = make([]*Result, 0)
+	s.mu.Unlock()
+
+	results := make([]*Result, 0, len(received))
+	for _, result := range received {
+		if enabled[result.SensorID] {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Close disconnects from the broker
+func (s *MQTTSensors) Close() {
+	s.client.Disconnect(250)
+}
+
+// ParseMQTTPayload reads temperature, humidity and the optional timestamp from a JSON payload.
+// Values may be numbers or numeric strings, the timestamp may be unix seconds or RFC 3339.
+func ParseMQTTPayload(payload []byte, config MQTTConfig) (*Reading, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+
+	temperature, err := payloadNumber(data, config.TemperatureField)
+	if err != nil {
+		return nil, err
+	}
+	humidity, err := payloadNumber(data, config.HumidityField)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now()
+	if config.TimestampField != "" {
+		if value, ok := payloadField(data, config.TimestampField); ok {
+			timestamp, err = parsePayloadTime(value)
+			if err != nil {
+				return nil, err
+			}
+			// stored in the local time zone like the readings of the other sources, so the time ranges
+			// compare as text
+			timestamp = timestamp.Local()
+		}
+	}
+
+	return &Reading{
+		Temperature: temperature,
+		Humidity:    humidity,
+		Timestamp:   timestamp,
+	}, nil
+}
+
+func payloadField(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func payloadNumber(data map[string]interface{}, path string) (float64, error) {
+	value, ok := payloadField(data, path)
+	if !ok {
+		return 0, fmt.Errorf("field %q missing", path)
+	}
+
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	default:
+		return 0, fmt.Errorf("field %q is not a number", path)
+	}
+}
+
+func parsePayloadTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		seconds := int64(v)
+		return time.Unix(seconds, int64((v-float64(seconds))*1e9)), nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsePayloadTime(seconds)
+		}
+		return time.Parse(time.RFC3339, v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported timestamp %v", value)
+	}
+}
+
+// ParseTopics parses a topic mapping in the format "topic=sensorID,..."
+func ParseTopics(value string) (map[string]int, error) {
+	topics := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		topic, id, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid topic mapping %q, expected topic=sensorID", entry)
+		}
+		sensorID, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensor ID in %q: %v", entry, err)
+		}
+		topic = strings.TrimSpace(topic)
+		if err := validateTopicFilter(topic); err != nil {
+			return nil, err
+		}
+		topics[topic] = sensorID
+	}
+	return topics, nil
+}
+
+// topicMatches reports whether a topic matches a subscription filter, + matches a single level and # all
+// remaining levels
+func topicMatches(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+	// wildcards in the first level do not match the topics of the broker like $SYS
+	if strings.HasPrefix(topic, "$") && (filterLevels[0] == "+" || filterLevels[0] == "#") {
+		return false
+	}
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+func validateTopicFilter(filter string) error {
+	if filter == "" {
+		return fmt.Errorf("empty MQTT topic")
+	}
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if strings.Contains(level, "#") && (level != "#" || i != len(levels)-1) {
+			return fmt.Errorf("invalid MQTT topic %q, # must be the whole last level", filter)
+		}
+		if strings.Contains(level, "+") && level != "+" {
+			return fmt.Errorf("invalid MQTT topic %q, + must be a whole level", filter)
+		}
+	}
+	return nil
+}
+
+// wildcardFilters returns the topics with wildcards from the most specific: filters without # first, then those
+// with fewer +
+func wildcardFilters(topics map[string]int) []string {
+	var filters []string
+	for topic := range topics {
+		if strings.ContainsAny(topic, "+#") {
+			filters = append(filters, topic)
+		}
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		multiI, multiJ := strings.HasSuffix(filters[i], "#"), strings.HasSuffix(filters[j], "#")
+		if multiI != multiJ {
+			return multiJ
+		}
+		if singleI, singleJ := strings.Count(filters[i], "+"), strings.Count(filters[j], "+"); singleI != singleJ {
+			return singleI < singleJ
+		}
+		return filters[i] < filters[j]
+	})
+	return filters
+}
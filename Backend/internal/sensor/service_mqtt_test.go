@@ -0,0 +1,498 @@
+package sensor
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+func TestParseMQTTPayload(t *testing.T) {
+	flat := MQTTConfig{TemperatureField: "temperature", HumidityField: "humidity", TimestampField: "time"}
+	nested := MQTTConfig{TemperatureField: "sensor.climate.temp", HumidityField: "sensor.climate.hum",
+		TimestampField: "sensor.time"}
+
+	tests := []struct {
+		name        string
+		payload     string
+		config      MQTTConfig
+		temperature float64
+		humidity    float64
+		// zero means the time the message was received
+		timestamp time.Time
+		wantErr   bool
+	}{
+		{
+			name:        "flat numbers",
+			payload:     `{"temperature": 21.5, "humidity": 48}`,
+			config:      flat,
+			temperature: 21.5,
+			humidity:    48,
+		},
+		{
+			name:        "numeric strings",
+			payload:     `{"temperature": "21.5", "humidity": "48.2"}`,
+			config:      flat,
+			temperature: 21.5,
+			humidity:    48.2,
+		},
+		{
+			name:        "nested paths",
+			payload:     `{"sensor": {"climate": {"temp": 19.25, "hum": 61}, "time": 1760680800}}`,
+			config:      nested,
+			temperature: 19.25,
+			humidity:    61,
+			timestamp:   time.Unix(1760680800, 0),
+		},
+		{
+			name:        "unix seconds",
+			payload:     `{"temperature": 20, "humidity": 50, "time": 1760680800}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+			timestamp:   time.Unix(1760680800, 0),
+		},
+		{
+			name:        "fractional unix seconds",
+			payload:     `{"temperature": 20, "humidity": 50, "time": 1760680800.25}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+			timestamp:   time.Unix(1760680800, 250000000),
+		},
+		{
+			name:        "unix seconds as string",
+			payload:     `{"temperature": 20, "humidity": 50, "time": "1760680800"}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+			timestamp:   time.Unix(1760680800, 0),
+		},
+		{
+			name:        "RFC 3339",
+			payload:     `{"temperature": 20, "humidity": 50, "time": "2025-10-17T08:00:00+02:00"}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+			timestamp:   time.Unix(1760680800, 0),
+		},
+		{
+			name:        "RFC 3339 with fractional seconds",
+			payload:     `{"temperature": 20, "humidity": 50, "time": "2025-10-17T06:00:00.5Z"}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+			timestamp:   time.Unix(1760680800, 500000000),
+		},
+		{
+			name:        "missing optional timestamp",
+			payload:     `{"temperature": 20, "humidity": 50}`,
+			config:      flat,
+			temperature: 20,
+			humidity:    50,
+		},
+		{
+			name:    "invalid timestamp",
+			payload: `{"temperature": 20, "humidity": 50, "time": "yesterday"}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported timestamp type",
+			payload: `{"temperature": 20, "humidity": 50, "time": true}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "missing temperature",
+			payload: `{"humidity": 50}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "missing humidity",
+			payload: `{"temperature": 20}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "missing nested field",
+			payload: `{"sensor": {"climate": {"temp": 19.25}}}`,
+			config:  nested,
+			wantErr: true,
+		},
+		{
+			name:    "path through a value",
+			payload: `{"sensor": {"climate": 19.25}}`,
+			config:  nested,
+			wantErr: true,
+		},
+		{
+			name:    "not a number",
+			payload: `{"temperature": "warm", "humidity": 50}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			payload: `{"temperature": [20], "humidity": 50}`,
+			config:  flat,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			payload: `{"temperature": 20,`,
+			config:  flat,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			reading, err := ParseMQTTPayload([]byte(tt.payload), tt.config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", reading)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if reading.Temperature != tt.temperature || reading.Humidity != tt.humidity {
+				t.Errorf("expected %.2f°C %.2f%%, got %.2f°C %.2f%%", tt.temperature, tt.humidity,
+					reading.Temperature, reading.Humidity)
+			}
+			if tt.timestamp.IsZero() {
+				if reading.Timestamp.Before(before) || reading.Timestamp.After(time.Now()) {
+					t.Errorf("expected the time of receipt, got %v", reading.Timestamp)
+				}
+			} else if !reading.Timestamp.Equal(tt.timestamp) {
+				t.Errorf("expected %v, got %v", tt.timestamp, reading.Timestamp)
+			}
+			if reading.Timestamp.Location() != time.Local {
+				t.Errorf("expected the timestamp in the local time zone, got %v", reading.Timestamp)
+			}
+		})
+	}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter string
+		topic  string
+		want   bool
+	}{
+		{filter: "home/bathroom", topic: "home/bathroom", want: true},
+		{filter: "home/bathroom", topic: "home/kitchen"},
+		{filter: "home/+/climate", topic: "home/bathroom/climate", want: true},
+		{filter: "home/+/climate", topic: "home/bathroom/light"},
+		{filter: "home/+/climate", topic: "home/climate"},
+		{filter: "home/+", topic: "home/bathroom/climate"},
+		{filter: "home/#", topic: "home/bathroom/climate", want: true},
+		{filter: "home/#", topic: "home", want: true},
+		{filter: "home/#", topic: "garden/shed"},
+		{filter: "#", topic: "garden/shed", want: true},
+		{filter: "+/shed", topic: "garden/shed", want: true},
+		{filter: "#", topic: "$SYS/broker/uptime"},
+		{filter: "+/broker/uptime", topic: "$SYS/broker/uptime"},
+		{filter: "$SYS/#", topic: "$SYS/broker/uptime", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter+" "+tt.topic, func(t *testing.T) {
+			if got := topicMatches(tt.filter, tt.topic); got != tt.want {
+				t.Errorf("topicMatches(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTopics(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]int
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[string]int{}},
+		{
+			name:  "topics and filters",
+			value: "home/bathroom=3, home/+/climate=4,garden/#=5",
+			want:  map[string]int{"home/bathroom": 3, "home/+/climate": 4, "garden/#": 5},
+		},
+		{name: "missing sensor", value: "home/bathroom", wantErr: true},
+		{name: "invalid sensor", value: "home/bathroom=bath", wantErr: true},
+		{name: "empty topic", value: "=3", wantErr: true},
+		{name: "# not last", value: "home/#/climate=3", wantErr: true},
+		{name: "# in a level", value: "home/bath#=3", wantErr: true},
+		{name: "+ in a level", value: "home/bath+/climate=3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topics, err := ParseTopics(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", topics)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(topics) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, topics)
+			}
+			for topic, sensorID := range tt.want {
+				if topics[topic] != sensorID {
+					t.Errorf("expected topic %q for sensor %d, got %d", topic, sensorID, topics[topic])
+				}
+			}
+		})
+	}
+}
+
+const testClientID = "sensors-test"
+
+// newTestBroker starts an embedded MQTT broker on a free local port and returns its URL
+func newTestBroker(t *testing.T) (*mqttserver.Server, string) {
+	t.Helper()
+	broker := mqttserver.New(&mqttserver.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	listener := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(listener); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	return broker, "tcp://" + listener.Address()
+}
+
+// newTestMQTTSensors registers the sensors and connects to the broker, it returns once the topics are subscribed
+func newTestMQTTSensors(t *testing.T, broker *mqttserver.Server, url string, sensors []*contracts.Sensor,
+	topics map[string]int) *MQTTSensors {
+	t.Helper()
+	registry, err := NewRegistry(newTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sensor := range sensors {
+		if err := registry.Save(sensor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewMQTTSensors(registry, MQTTConfig{Broker: url, ClientID: testClientID, Topics: topics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	waitForSubscriptions(t, broker, len(topics))
+	return s
+}
+
+// waitForSubscriptions waits until the test client is connected and subscribed to the number of topics
+func waitForSubscriptions(t *testing.T, broker *mqttserver.Server, count int) {
+	t.Helper()
+	waitFor(t, "the subscriptions", func() bool {
+		client, ok := broker.Clients.Get(testClientID)
+		return ok && !client.Closed() && len(client.State.Subscriptions.GetAll()) == count
+	})
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func publish(t *testing.T, broker *mqttserver.Server, topic string, payload string) {
+	t.Helper()
+	if err := broker.Publish(topic, []byte(payload), false, 1); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForTemperature waits until the latest reading of the sensor has the temperature, the messages of a
+// subscription are delivered in order, so the messages published before have been received too
+func waitForTemperature(t *testing.T, s *MQTTSensors, sensorID int, temperature float64) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("%.1f°C of sensor %d", temperature, sensorID), func() bool {
+		reading, err := s.ReadSensor(sensorID)
+		return err == nil && reading.Temperature == temperature
+	})
+}
+
+func TestMQTTSensorsSubscribeWithQoS1(t *testing.T) {
+	broker, url := newTestBroker(t)
+	topics := map[string]int{"home/bathroom": 3, "garden/+/climate": 4, "cellar/#": 5}
+	newTestMQTTSensors(t, broker, url, nil, topics)
+
+	client, _ := broker.Clients.Get(testClientID)
+	subscriptions := client.State.Subscriptions.GetAll()
+	for topic := range topics {
+		subscription, ok := subscriptions[topic]
+		if !ok {
+			t.Errorf("topic %q is not subscribed", topic)
+			continue
+		}
+		if subscription.Qos != 1 {
+			t.Errorf("topic %q is subscribed with QoS %d, expected 1", topic, subscription.Qos)
+		}
+	}
+}
+
+func TestMQTTSensorsReadAllSensors(t *testing.T) {
+	broker, url := newTestBroker(t)
+	s := newTestMQTTSensors(t, broker, url, []*contracts.Sensor{
+		{ID: 3, Name: "Bathroom", Source: SourceMQTT, Enabled: true},
+		{ID: 4, Name: "Cellar", Source: SourceMQTT, Enabled: false},
+		{ID: 7, Name: "Shed", Source: SourceMQTT, Enabled: true},
+		{ID: 8, Name: "Garden", Source: SourceMQTT, Enabled: true},
+	}, map[string]int{"home/bathroom": 3, "home/cellar": 4, "home/kitchen": 5, "garden/+/climate": 7,
+		"garden/#": 8})
+
+	publish(t, broker, "home/bathroom", `{"temperature": 22.5, "humidity": 71}`)
+	publish(t, broker, "home/bathroom", `{"temperature": "hot"}`)
+	// disabled, not registered and not subscribed
+	publish(t, broker, "home/cellar", `{"temperature": 12, "humidity": 80}`)
+	publish(t, broker, "home/kitchen", `{"temperature": 21, "humidity": 50}`)
+	publish(t, broker, "home/garage", `{"temperature": 9, "humidity": 60}`)
+	// the more specific filter wins
+	publish(t, broker, "garden/shed/climate", `{"temperature": 11, "humidity": 81}`)
+	publish(t, broker, "garden/pond", `{"temperature": 10, "humidity": 90}`)
+	publish(t, broker, "home/bathroom", `{"temperature": 22.7, "humidity": 74}`)
+	waitForTemperature(t, s, 3, 22.7)
+
+	results, err := s.ReadAllSensors()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantSensors := []int{3, 3, 7, 8, 3}
+	if len(results) != len(wantSensors) {
+		t.Fatalf("expected %d results, got %d", len(wantSensors), len(results))
+	}
+	for i, result := range results {
+		if result.SensorID != wantSensors[i] {
+			t.Errorf("expected result %d of sensor %d, got sensor %d", i, wantSensors[i], result.SensorID)
+		}
+	}
+	if results[0].Err != nil || results[0].Reading.Temperature != 22.5 || results[0].Reading.SensorID != 3 {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+	if results[1].Err == nil || results[1].Reading != nil {
+		t.Errorf("expected the invalid payload as error, got %+v", results[1])
+	}
+	if results[2].Err != nil || results[2].Reading.Temperature != 11 {
+		t.Errorf("unexpected result of the wildcard topic %+v", results[2])
+	}
+	if results[4].Err != nil || results[4].Reading.Humidity != 74 {
+		t.Errorf("unexpected last result %+v", results[4])
+	}
+
+	// the buffer is drained by reading it
+	if results, err := s.ReadAllSensors(); err != nil || len(results) != 0 {
+		t.Errorf("expected no further results, got %d, %v", len(results), err)
+	}
+
+	// the invalid payload does not replace the latest reading
+	reading, err := s.ReadSensor(3)
+	if err != nil || reading.Temperature != 22.7 {
+		t.Errorf("expected the latest valid reading, got %+v, %v", reading, err)
+	}
+	if _, err := s.ReadSensor(6); err == nil {
+		t.Error("expected an error for a sensor without messages")
+	}
+}
+
+func TestMQTTSensorsResubscribeAfterReconnect(t *testing.T) {
+	broker, url := newTestBroker(t)
+	s := newTestMQTTSensors(t, broker, url, []*contracts.Sensor{
+		{ID: 3, Name: "Bathroom", Source: SourceMQTT, Enabled: true},
+	}, map[string]int{"home/bathroom": 3})
+
+	publish(t, broker, "home/bathroom", `{"temperature": 22.5, "humidity": 71}`)
+	waitForTemperature(t, s, 3, 22.5)
+
+	// the broker drops the connection, with a clean session the subscriptions are gone
+	client, _ := broker.Clients.Get(testClientID)
+	client.Stop(errors.New("connection lost"))
+	waitFor(t, "the reconnect", func() bool {
+		reconnected, ok := broker.Clients.Get(testClientID)
+		return ok && reconnected != client
+	})
+	waitForSubscriptions(t, broker, 1)
+
+	publish(t, broker, "home/bathroom", `{"temperature": 23.1, "humidity": 70}`)
+	waitForTemperature(t, s, 3, 23.1)
+}
+
+func TestMQTTSensorsConfigRejectsInvalidTopics(t *testing.T) {
+	registry, err := NewRegistry(newTestDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the topics are validated before connecting, the broker is never reached
+	_, err = NewMQTTSensors(registry, MQTTConfig{Broker: "tcp://127.0.0.1:1", Topics: map[string]int{"home/#/x": 3}})
+	if err == nil {
+		t.Error("expected an error for an invalid topic filter")
+	}
+}
+
+// fakeMessage is a received MQTT message without a broker
+type fakeMessage struct {
+	topic   string
+	payload string
+}
+
+func (m fakeMessage) Duplicate() bool   { return false }
+func (m fakeMessage) Qos() byte         { return 1 }
+func (m fakeMessage) Retained() bool    { return false }
+func (m fakeMessage) Topic() string     { return m.topic }
+func (m fakeMessage) MessageID() uint16 { return 1 }
+func (m fakeMessage) Payload() []byte   { return []byte(m.payload) }
+func (m fakeMessage) Ack()              {}
+
+func TestMQTTSensorsBufferLimit(t *testing.T) {
+	broker, url := newTestBroker(t)
+	s := newTestMQTTSensors(t, broker, url, []*contracts.Sensor{
+		{ID: 3, Name: "Bathroom", Source: SourceMQTT, Enabled: true},
+	}, map[string]int{"home/bathroom": 3})
+
+	// passed to the handler directly, the broker would drop messages of a slow subscriber itself
+	for i := 0; i < maxBufferedResults+5; i++ {
+		s.onMessage(nil, fakeMessage{topic: "home/bathroom", payload: `{"temperature": 22.5, "humidity": 71}`})
+	}
+
+	results, err := s.ReadAllSensors()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != maxBufferedResults {
+		t.Errorf("expected %d buffered results, got %d", maxBufferedResults, len(results))
+	}
+}
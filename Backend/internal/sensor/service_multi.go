@@ -0,0 +1,61 @@
+package sensor
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// MultiService combines several services, e.g. the DHT22 sensors in redis and the ESP32 nodes via MQTT
+type MultiService struct {
+	services []Service
+}
+
+func NewMultiService(services ...Service) *MultiService {
+	return &MultiService{services: services}
+}
+
+func (m *MultiService) ReadSensor(sensorID int) (*Reading, error) {
+	var errs []error
+	for _, service := range m.services {
+		reading, err := service.ReadSensor(sensorID)
+		if err == nil {
+			return reading, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("no service could read sensor %d: %w", sensorID, errors.Join(errs...))
+}
+
+// ReadAllSensors returns the results of all services, it only fails if every service failed
+func (m *MultiService) ReadAllSensors() ([]*Result, error) {
+	var results []*Result
+	var errs []error
+	for _, service := range m.services {
+		res, err := service.ReadAllSensors()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		results = append(results, res...)
+	}
+
+	if len(errs) > 0 && len(errs) == len(m.services) {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Printf("Error reading sensors of one service: %v", err)
+	}
+	return results, nil
+}
+
+// Acknowledge forwards the readings to all services that need acknowledgements
+func (m *MultiService) Acknowledge(readings []*Reading) error {
+	var errs []error
+	for _, service := range m.services {
+		if acknowledger, ok := service.(Acknowledger); ok {
+			errs = append(errs, acknowledger.Acknowledge(readings))
+		}
+	}
+	return errors.Join(errs...)
+}
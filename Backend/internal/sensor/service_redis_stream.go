@@ -0,0 +1,158 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"strings"
+)
+
+// RedisStreamSensors consumes the readings written by dht22.py to a Redis Stream with a consumer group.
+// Every entry is delivered once; entries are acknowledged after they have been processed, so unprocessed
+// entries are delivered again after a restart.
+//
+// A stream entry has the fields 'sensor' (the redis key of the sensor in the registry, e.g. 'sensor1')
+// and 'data' (the same JSON written to the key).
+type RedisStreamSensors struct {
+	rdb      *redis.Client
+	registry *Registry
+	stream   string
+	group    string
+	consumer string
+}
+
+func NewRedisStreamSensors(rdb *redis.Client, registry *Registry, stream, group, consumer string) (*RedisStreamSensors, error) {
+	err := rdb.XGroupCreateMkStream(context.Background(), stream, group, "0").Err()
+	if err != nil && !isBusyGroupErr(err) {
+		return nil, fmt.Errorf("cannot create consumer group %q: %w", group, err)
+	}
+
+	return &RedisStreamSensors{
+		rdb:      rdb,
+		registry: registry,
+		stream:   stream,
+		group:    group,
+		consumer: consumer,
+	}, nil
+}
+
+func isBusyGroupErr(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+// ReadSensor returns the newest entry of the sensor in the stream without consuming it
+func (s *RedisStreamSensors) ReadSensor(sensorID int) (*Reading, error) {
+	sensor, err := s.registry.Get(sensorID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages, err := s.rdb.XRevRangeN(context.Background(), s.stream, "+", "-", 100).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		if key, _ := message.Values["sensor"].(string); key == sensor.RedisKey {
+			return s.parse(sensorID, message)
+		}
+	}
+
+	return nil, fmt.Errorf("no entry for sensor %d in the last 100 stream entries", sensorID)
+}
+
+// ReadAllSensors returns all entries not processed yet, first the pending ones of a previous read,
+// then the new ones. A sensor may occur several times in the result.
+func (s *RedisStreamSensors) ReadAllSensors() ([]*Result, error) {
+	sensors, err := s.registry.GetEnabledBySource(SourceRedis)
+	if err != nil {
+		return nil, err
+	}
+	sensorIDs := make(map[string]int, len(sensors))
+	for _, sensor := range sensors {
+		sensorIDs[sensor.RedisKey] = sensor.ID
+	}
+
+	// "0" returns the entries delivered to this consumer but never acknowledged, ">" the new ones
+	var messages []redis.XMessage
+	for _, id := range []string{"0", ">"} {
+		res, err := s.rdb.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+			Group:    s.group,
+			Consumer: s.consumer,
+			Streams:  []string{s.stream, id},
+			Count:    1000,
+			Block:    -1, // don't block
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return nil, err
+		}
+		for _, stream := range res {
+			messages = append(messages, stream.Messages...)
+		}
+	}
+
+	results := make([]*Result, 0, len(messages))
+	var skipped []string
+	for _, message := range messages {
+		key, _ := message.Values["sensor"].(string)
+		sensorID, ok := sensorIDs[key]
+		if !ok {
+			// not registered or disabled, acknowledge it so it is not delivered again
+			skipped = append(skipped, message.ID)
+			continue
+		}
+
+		reading, err := s.parse(sensorID, message)
+		if err != nil {
+			log.Printf("Invalid stream entry %s: %v", message.ID, err)
+			skipped = append(skipped, message.ID)
+		}
+		results = append(results, &Result{SensorID: sensorID, Reading: reading, Err: err})
+	}
+
+	if len(skipped) > 0 {
+		if err := s.rdb.XAck(context.Background(), s.stream, s.group, skipped...).Err(); err != nil {
+			log.Printf("Error acknowledging skipped stream entries: %v", err)
+		}
+	}
+
+	return results, nil
+}
+
+// Acknowledge marks the readings as processed, they will not be delivered again
+func (s *RedisStreamSensors) Acknowledge(readings []*Reading) error {
+	ids := make([]string, 0, len(readings))
+	for _, reading := range readings {
+		if reading.MessageID != "" {
+			ids = append(ids, reading.MessageID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return s.rdb.XAck(context.Background(), s.stream, s.group, ids...).Err()
+}
+
+func (s *RedisStreamSensors) parse(sensorID int, message redis.XMessage) (*Reading, error) {
+	data, ok := message.Values["data"].(string)
+	if !ok {
+		return nil, fmt.Errorf("stream entry %s has no data", message.ID)
+	}
+
+	var reading sensorReading
+	if err := json.Unmarshal([]byte(data), &reading); err != nil {
+		return nil, err
+	}
+
+	return &Reading{
+		SensorID:    sensorID,
+		Temperature: reading.TemperatureC,
+		Humidity:    reading.Humidity,
+		Timestamp:   unixTime(reading.Timestamp),
+		MessageID:   message.ID,
+	}, nil
+}
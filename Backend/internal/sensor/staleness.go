@@ -0,0 +1,113 @@
+package sensor
+
+import (
+	"sync"
+	"time"
+)
+
+// StalenessMonitor tracks the age of the last fresh value of each sensor. A value is fresh if its
+// timestamp advanced, a sensor is stale if its last fresh value is older than the threshold.
+type StalenessMonitor struct {
+	threshold time.Duration
+	startedAt time.Time
+
+	mu        sync.RWMutex
+	lastFresh map[int]time.Time
+	stale     map[int]bool
+
+	onStale []func(sensorID int, lastFresh time.Time)
+	onFresh []func(sensorID int)
+}
+
+func NewStalenessMonitor(threshold time.Duration) *StalenessMonitor {
+	return &StalenessMonitor{
+		threshold: threshold,
+		startedAt: time.Now(),
+		lastFresh: map[int]time.Time{},
+		stale:     map[int]bool{},
+		onStale:   make([]func(sensorID int, lastFresh time.Time), 0),
+		onFresh:   make([]func(sensorID int), 0),
+	}
+}
+
+// OnStale registers a function called when a sensor becomes stale
+func (m *StalenessMonitor) OnStale(fn func(sensorID int, lastFresh time.Time)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onStale = append(m.onStale, fn)
+}
+
+// OnFresh registers a function called when a stale sensor delivers fresh values again
+func (m *StalenessMonitor) OnFresh(fn func(sensorID int)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onFresh = append(m.onFresh, fn)
+}
+
+// Observe registers a sensor, without fresh values it becomes stale a threshold after the monitor started
+func (m *StalenessMonitor) Observe(sensorID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lastFresh[sensorID]; !ok {
+		m.lastFresh[sensorID] = time.Time{}
+	}
+}
+
+// Fresh records a new value of the sensor with the timestamp reported by the sensor
+func (m *StalenessMonitor) Fresh(sensorID int, timestamp time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if timestamp.After(m.lastFresh[sensorID]) {
+		m.lastFresh[sensorID] = timestamp
+	}
+}
+
+// Check updates the stale state of all observed sensors and calls the registered functions on changes
+func (m *StalenessMonitor) Check(now time.Time) {
+	m.mu.Lock()
+	var becameStale, becameFresh []int
+	for sensorID, lastFresh := range m.lastFresh {
+		reference := lastFresh
+		if reference.IsZero() {
+			reference = m.startedAt
+		}
+
+		stale := now.Sub(reference) > m.threshold
+		if stale && !m.stale[sensorID] {
+			becameStale = append(becameStale, sensorID)
+		} else if !stale && m.stale[sensorID] {
+			becameFresh = append(becameFresh, sensorID)
+		}
+		m.stale[sensorID] = stale
+	}
+	lastFresh := make(map[int]time.Time, len(m.lastFresh))
+	for sensorID, timestamp := range m.lastFresh {
+		lastFresh[sensorID] = timestamp
+	}
+	onStale := append([]func(int, time.Time){}, m.onStale...)
+	onFresh := append([]func(int){}, m.onFresh...)
+	m.mu.Unlock()
+
+	for _, sensorID := range becameStale {
+		for _, fn := range onStale {
+			fn(sensorID, lastFresh[sensorID])
+		}
+	}
+	for _, sensorID := range becameFresh {
+		for _, fn := range onFresh {
+			fn(sensorID)
+		}
+	}
+}
+
+// State returns the timestamp of the last fresh value and whether the sensor is stale
+func (m *StalenessMonitor) State(sensorID int) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lastFresh[sensorID], m.stale[sensorID]
+}
@@ -0,0 +1,325 @@
+package ventilation
+
+import (
+	"BeRoHuTe/internal/climate"
+	"BeRoHuTe/internal/contracts"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// temperature difference in °C to the temperature before the ventilation at which the room counts as recovered
+const recoveryTolerance = 0.5
+
+type ButtonRepository interface {
+	GetAll(offset int, limit int) ([]*contracts.ButtonReading, error)
+}
+
+type SensorRepository interface {
+	GetInBetween(start time.Time, end time.Time) ([]*contracts.SensorReading, error)
+}
+
+type AnalyzerOption func(*Analyzer) error
+
+// WithWindows sets how long before opening and after closing the window the readings are analyzed
+func WithWindows(before time.Duration, after time.Duration) AnalyzerOption {
+	return func(a *Analyzer) error {
+		if before <= 0 || after <= 0 {
+			return errors.New("analysis windows must be positive")
+		}
+		a.before = before
+		a.after = after
+		return nil
+	}
+}
+
+// WithMaxAge sets how old a ventilation may be to still be analyzed, older readings are most likely cleaned up
+func WithMaxAge(age time.Duration) AnalyzerOption {
+	return func(a *Analyzer) error {
+		a.maxAge = age
+		return nil
+	}
+}
+
+// WithButtonSensors limits the analysis of a button's ventilations to the sensor of its room (button ID to sensor ID),
+// buttons without an entry are analyzed for all sensors
+func WithButtonSensors(sensors map[int]int) AnalyzerOption {
+	return func(a *Analyzer) error {
+		a.buttonSensors = sensors
+		return nil
+	}
+}
+
+// Analyzer measures the effect of every ventilation on the rooms once the readings after it are available
+type Analyzer struct {
+	btnRepo    ButtonRepository
+	sensorRepo SensorRepository
+	repo       AnalysisRepository
+	now        func() time.Time
+
+	before time.Duration
+	after  time.Duration
+	maxAge time.Duration
+
+	buttonSensors map[int]int
+
+	mu    sync.Mutex
+	start bool
+	stop  chan bool
+}
+
+func NewAnalyzer(btnRepo ButtonRepository, sensorRepo SensorRepository, repo AnalysisRepository,
+	options ...AnalyzerOption) (*Analyzer, error) {
+	a := &Analyzer{
+		btnRepo:    btnRepo,
+		sensorRepo: sensorRepo,
+		repo:       repo,
+		now:        time.Now,
+		before:     10 * time.Minute,
+		after:      time.Hour,
+		maxAge:     48 * time.Hour,
+	}
+
+	for _, option := range options {
+		if err := option(a); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// AfterWindow returns how long after a ventilation its readings are needed for the analysis
+func (a *Analyzer) AfterWindow() time.Duration {
+	return a.after
+}
+
+func (a *Analyzer) Start(ctx context.Context, interval time.Duration) {
+	if a.start {
+		return
+	}
+	a.start = true
+	a.stop = make(chan bool)
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.stop:
+				return
+			case <-ticker.C:
+				if _, err := a.AnalyzePending(); err != nil {
+					log.Printf("[VentilationAnalysis] Error: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (a *Analyzer) Stop() {
+	if !a.start {
+		return
+	}
+	close(a.stop)
+	a.start = false
+}
+
+// AnalyzePending analyzes all ventilations that are complete, not older than the max age and not analyzed yet.
+// A ventilation failing to be analyzed is logged and tried again with the next pass.
+func (a *Analyzer) AnalyzePending() (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	perPage := 10
+	counter := 0
+
+	for offset := 0; ; offset += perPage {
+		readings, err := a.btnRepo.GetAll(offset, perPage)
+		if err != nil {
+			return counter, err
+		}
+
+		recent := false
+		for _, reading := range readings {
+			if reading.EndAt == nil || now.Sub(*reading.EndAt) > a.maxAge {
+				continue
+			}
+			recent = true
+
+			// the room did not settle yet
+			if reading.EndAt.Add(a.after).After(now) {
+				continue
+			}
+
+			analyzed, err := a.repo.IsAnalyzed(reading.ID)
+			if err != nil {
+				return counter, err
+			}
+			if analyzed {
+				continue
+			}
+
+			count, err := a.Analyze(reading)
+			if err != nil {
+				log.Printf("[VentilationAnalysis] Error analyzing ventilation %d: %v", reading.ID, err)
+			}
+			counter += count
+		}
+
+		// the button readings are ordered newest first
+		if !recent {
+			break
+		}
+	}
+
+	if counter > 0 {
+		log.Printf("[VentilationAnalysis] Stored %d analyses", counter)
+	}
+	return counter, nil
+}
+
+// Analyze stores the effect of a ventilation for each sensor with readings before and during it, all at once.
+// A ventilation without any result is marked as skipped, so it is not analyzed again.
+func (a *Analyzer) Analyze(reading *contracts.ButtonReading) (int, error) {
+	if reading.EndAt == nil {
+		return 0, fmt.Errorf("ventilation %d is still in progress", reading.ID)
+	}
+
+	sensorReadings, err := a.sensorRepo.GetInBetween(reading.StartAt.Add(-a.before), reading.EndAt.Add(a.after))
+	if err != nil {
+		return 0, err
+	}
+
+	roomSensor, hasRoom := a.buttonSensors[reading.ButtonID]
+	bySensor := make(map[int][]*contracts.SensorReading)
+	for _, sensorReading := range sensorReadings {
+		if hasRoom && sensorReading.SensorID != roomSensor {
+			continue
+		}
+		bySensor[sensorReading.SensorID] = append(bySensor[sensorReading.SensorID], sensorReading)
+	}
+
+	var analyses []*contracts.VentilationAnalysis
+	var skipped []string
+	for sensorID, readings := range bySensor {
+		sort.Slice(readings, func(i, j int) bool {
+			return readings[i].Timestamp.Before(readings[j].Timestamp)
+		})
+
+		analysis, ok := a.analyze(reading, readings)
+		if !ok {
+			log.Printf("[VentilationAnalysis] Not enough readings of sensor %d for ventilation %d", sensorID, reading.ID)
+			skipped = append(skipped, fmt.Sprintf("sensor %d", sensorID))
+			continue
+		}
+
+		analyses = append(analyses, analysis)
+	}
+
+	if len(analyses) > 0 {
+		if err := a.repo.SaveAll(analyses); err != nil {
+			return 0, err
+		}
+		return len(analyses), nil
+	}
+
+	reason := "no sensor readings"
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		reason = "not enough readings before and during the ventilation: " + strings.Join(skipped, ", ")
+	}
+	if err := a.repo.SaveSkipped(reading.ID, reason, a.now()); err != nil {
+		return 0, err
+	}
+	log.Printf("[VentilationAnalysis] Skipped ventilation %d: %s", reading.ID, reason)
+	return 0, nil
+}
+
+// analyze expects the readings of a single sensor ordered by time
+func (a *Analyzer) analyze(reading *contracts.ButtonReading, readings []*contracts.SensorReading) (*contracts.VentilationAnalysis, bool) {
+	endAt := *reading.EndAt
+	var before, during, after []*contracts.SensorReading
+	// the room settled in the last part of the after window, as long as the before window
+	settledAt := endAt.Add(a.after - a.before)
+	for _, r := range readings {
+		switch {
+		case r.Timestamp.Before(reading.StartAt):
+			before = append(before, r)
+		case !r.Timestamp.After(endAt):
+			during = append(during, r)
+		case !r.Timestamp.Before(settledAt):
+			after = append(after, r)
+		}
+	}
+	if len(before) == 0 || len(during) == 0 {
+		return nil, false
+	}
+
+	analysis := &contracts.VentilationAnalysis{
+		ButtonReadingID: reading.ID,
+		ButtonID:        reading.ButtonID,
+		SensorID:        readings[0].SensorID,
+		StartAt:         reading.StartAt,
+		EndAt:           endAt,
+		Before:          average(before),
+		During:          lowest(during),
+		CreatedAt:       a.now(),
+	}
+	if len(after) > 0 {
+		metrics := average(after)
+		analysis.After = &metrics
+	}
+	analysis.AbsoluteHumidityDrop = climate.Round(analysis.Before.AbsoluteHumidity-analysis.During.AbsoluteHumidity, 2)
+	analysis.TemperatureLoss = climate.Round(analysis.Before.Temperature-analysis.During.Temperature, 1)
+
+	for _, r := range readings {
+		if r.Timestamp.After(endAt) && r.Temperature >= analysis.Before.Temperature-recoveryTolerance {
+			analysis.Recovered = true
+			analysis.RecoveryMinutes = climate.Round(r.Timestamp.Sub(endAt).Minutes(), 1)
+			break
+		}
+	}
+
+	return analysis, true
+}
+
+func average(readings []*contracts.SensorReading) contracts.VentilationMetrics {
+	var average climate.Average
+	for _, r := range readings {
+		average.Add(r.Temperature, r.Humidity)
+	}
+	metrics := average.Metrics()
+	return contracts.VentilationMetrics{
+		Temperature:      metrics.Temperature,
+		Humidity:         metrics.Humidity,
+		AbsoluteHumidity: metrics.AbsoluteHumidity,
+	}
+}
+
+// lowest returns the lowest temperature and the reading with the lowest absolute humidity
+func lowest(readings []*contracts.SensorReading) contracts.VentilationMetrics {
+	metrics := contracts.VentilationMetrics{
+		Temperature:      readings[0].Temperature,
+		Humidity:         readings[0].Humidity,
+		AbsoluteHumidity: climate.AbsoluteHumidity(readings[0].Temperature, readings[0].Humidity),
+	}
+	for _, r := range readings[1:] {
+		metrics.Temperature = min(metrics.Temperature, r.Temperature)
+		if absoluteHumidity := climate.AbsoluteHumidity(r.Temperature, r.Humidity); absoluteHumidity < metrics.AbsoluteHumidity {
+			metrics.Humidity = r.Humidity
+			metrics.AbsoluteHumidity = absoluteHumidity
+		}
+	}
+	metrics.AbsoluteHumidity = climate.Round(metrics.AbsoluteHumidity, 2)
+	return metrics
+}
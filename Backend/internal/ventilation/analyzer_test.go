@@ -0,0 +1,178 @@
+package ventilation
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// fakeSensorRepository returns its readings in the requested range
+type fakeSensorRepository struct {
+	readings []*contracts.SensorReading
+}
+
+func (f *fakeSensorRepository) GetInBetween(start time.Time, end time.Time) ([]*contracts.SensorReading, error) {
+	var readings []*contracts.SensorReading
+	for _, reading := range f.readings {
+		if !reading.Timestamp.Before(start) && !reading.Timestamp.After(end) {
+			readings = append(readings, reading)
+		}
+	}
+	return readings, nil
+}
+
+func newTestAnalysisRepository(t *testing.T) AnalysisRepository {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in-memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewAnalysisRepository(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+// ventilationReadings returns readings of a sensor every 5 minutes from 10 minutes before to 60 minutes after
+// the ventilation, the humidity drops while the window is open
+func ventilationReadings(sensorID int, start time.Time, end time.Time, temperature float64) []*contracts.SensorReading {
+	var readings []*contracts.SensorReading
+	for at := start.Add(-10 * time.Minute); !at.After(end.Add(time.Hour)); at = at.Add(5 * time.Minute) {
+		humidity := 65.0
+		if !at.Before(start) && !at.After(end) {
+			humidity = 50
+		}
+		readings = append(readings, &contracts.SensorReading{SensorID: sensorID, Temperature: temperature,
+			Humidity: humidity, Timestamp: at})
+	}
+	return readings
+}
+
+func TestAnalyze(t *testing.T) {
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	end := start.Add(10 * time.Minute)
+	ventilation := &contracts.ButtonReading{ID: 1, ButtonID: 1, StartAt: start, EndAt: &end}
+
+	tests := []struct {
+		name     string
+		readings []*contracts.SensorReading
+		// sensors of the button's room, nil for all sensors
+		buttonSensors map[int]int
+		want          int
+		wantErr       bool
+		wantAnalyzed  bool
+	}{
+		{
+			name:         "all sensors",
+			readings:     append(ventilationReadings(1, start, end, 21), ventilationReadings(2, start, end, 19)...),
+			want:         2,
+			wantAnalyzed: true,
+		},
+		{
+			name:          "sensor of the room",
+			readings:      append(ventilationReadings(1, start, end, 21), ventilationReadings(2, start, end, 19)...),
+			buttonSensors: map[int]int{1: 2},
+			want:          1,
+			wantAnalyzed:  true,
+		},
+		{
+			name: "sensor without readings before the ventilation",
+			readings: append(ventilationReadings(1, start, end, 21), &contracts.SensorReading{SensorID: 2,
+				Temperature: 19, Humidity: 50, Timestamp: start.Add(time.Minute)}),
+			want:         1,
+			wantAnalyzed: true,
+		},
+		{
+			name:         "no readings",
+			wantAnalyzed: true,
+		},
+		{
+			// the analysis of sensor 2 fails to be stored, the one of sensor 1 must not be stored without it
+			name: "failed save",
+			readings: append(ventilationReadings(1, start, end, 21),
+				ventilationReadings(2, start, end, math.NaN())...),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestAnalysisRepository(t)
+			analyzer, err := NewAnalyzer(nil, &fakeSensorRepository{readings: tt.readings}, repo,
+				WithButtonSensors(tt.buttonSensors))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			count, err := analyzer.Analyze(ventilation)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Analyze() error = %v, want error %v", err, tt.wantErr)
+			}
+			if count != tt.want {
+				t.Errorf("Analyze() = %d analyses, want %d", count, tt.want)
+			}
+
+			analyses, err := repo.GetAll(0, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(analyses) != tt.want {
+				t.Errorf("stored %d analyses, want %d", len(analyses), tt.want)
+			}
+			analyzed, err := repo.IsAnalyzed(ventilation.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if analyzed != tt.wantAnalyzed {
+				t.Errorf("IsAnalyzed() = %v, want %v", analyzed, tt.wantAnalyzed)
+			}
+		})
+	}
+}
+
+func TestAnalyzeMetrics(t *testing.T) {
+	start := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	end := start.Add(10 * time.Minute)
+	readings := ventilationReadings(1, start, end, 21)
+	// the room cools down while the window is open and recovers 20 minutes after closing it
+	for _, reading := range readings {
+		if reading.Timestamp.After(start) && reading.Timestamp.Before(end.Add(20*time.Minute)) {
+			reading.Temperature = 18
+		}
+	}
+
+	analyzer, err := NewAnalyzer(nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, ok := analyzer.analyze(&contracts.ButtonReading{ID: 1, StartAt: start, EndAt: &end}, readings)
+	if !ok {
+		t.Fatal("expected an analysis")
+	}
+
+	if analysis.Before.Humidity != 65 || analysis.During.Humidity != 50 {
+		t.Errorf("humidity %.0f%% before and %.0f%% during, want 65%% and 50%%", analysis.Before.Humidity,
+			analysis.During.Humidity)
+	}
+	if analysis.TemperatureLoss != 3 {
+		t.Errorf("temperature loss %.1f°C, want 3°C", analysis.TemperatureLoss)
+	}
+	if analysis.AbsoluteHumidityDrop <= 0 {
+		t.Errorf("absolute humidity drop %.2f g/m³, want a positive drop", analysis.AbsoluteHumidityDrop)
+	}
+	if !analysis.Recovered || analysis.RecoveryMinutes != 20 {
+		t.Errorf("recovered %v after %.0f minutes, want 20 minutes", analysis.Recovered, analysis.RecoveryMinutes)
+	}
+	if analysis.After == nil || analysis.After.Temperature != 21 {
+		t.Errorf("after %+v, want the settled room at 21°C", analysis.After)
+	}
+}
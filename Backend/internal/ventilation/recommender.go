@@ -0,0 +1,285 @@
+// Package ventilation combines indoor readings with the outdoor weather to decide whether airing a room helps
+package ventilation
+
+import (
+	"BeRoHuTe/internal/climate"
+	"BeRoHuTe/internal/contracts"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ActionVentilate     = "ventilate"
+	ActionDontVentilate = "dont_ventilate"
+	ActionUnknown       = "unknown"
+)
+
+type IndoorRepository interface {
+	GetLatest() ([]*contracts.SensorReading, error)
+}
+
+type OutdoorRepository interface {
+	GetLatest() ([]*contracts.WeatherData, error)
+}
+
+type SensorRegistry interface {
+	GetEnabled() ([]*contracts.Sensor, error)
+}
+
+type Option func(*Recommender) error
+
+// WithMinDifference sets how much drier (in g/m³) the outdoor air has to be to recommend ventilating
+func WithMinDifference(difference float64) Option {
+	return func(r *Recommender) error {
+		if difference < 0 {
+			return errors.New("minimum difference must not be negative")
+		}
+		r.minDifference = difference
+		return nil
+	}
+}
+
+// WithTargetHumidity sets the indoor relative humidity in % below which there is no need to ventilate
+func WithTargetHumidity(humidity float64) Option {
+	return func(r *Recommender) error {
+		if humidity <= 0 || humidity > 100 {
+			return errors.New("target humidity must be between 0 and 100")
+		}
+		r.targetHumidity = humidity
+		return nil
+	}
+}
+
+// WithMinIndoorTemperature sets the room temperature in °C below which only short ventilation is recommended
+func WithMinIndoorTemperature(temperature float64) Option {
+	return func(r *Recommender) error {
+		r.minIndoorTemperature = temperature
+		return nil
+	}
+}
+
+// WithMaxWeatherAge sets how old the outdoor weather data may be before no recommendation is given
+func WithMaxWeatherAge(age time.Duration) Option {
+	return func(r *Recommender) error {
+		if age <= 0 {
+			return errors.New("maximum weather age must be positive")
+		}
+		r.maxWeatherAge = age
+		return nil
+	}
+}
+
+// WithMaxIndoorAge sets how old the latest reading of a sensor may be before no recommendation is given for it
+func WithMaxIndoorAge(age time.Duration) Option {
+	return func(r *Recommender) error {
+		if age <= 0 {
+			return errors.New("maximum indoor reading age must be positive")
+		}
+		r.maxIndoorAge = age
+		return nil
+	}
+}
+
+// WithSensorRegistry only recommends for the enabled sensors, without it every sensor with a reading is included
+func WithSensorRegistry(registry SensorRegistry) Option {
+	return func(r *Recommender) error {
+		r.registry = registry
+		return nil
+	}
+}
+
+// WithWeatherLocation sets the weather location whose outdoor air the rooms are ventilated with,
+// without it the first location is used
+func WithWeatherLocation(name string) Option {
+	return func(r *Recommender) error {
+		r.weatherLocation = name
+		return nil
+	}
+}
+
+type Recommender struct {
+	indoor   IndoorRepository
+	outdoor  OutdoorRepository
+	registry SensorRegistry
+	now      func() time.Time
+
+	minDifference        float64
+	targetHumidity       float64
+	minIndoorTemperature float64
+	maxWeatherAge        time.Duration
+	maxIndoorAge         time.Duration
+	weatherLocation      string
+}
+
+func NewRecommender(indoor IndoorRepository, outdoor OutdoorRepository, options ...Option) (*Recommender, error) {
+	r := &Recommender{
+		indoor:               indoor,
+		outdoor:              outdoor,
+		now:                  time.Now,
+		minDifference:        1,
+		targetHumidity:       50,
+		minIndoorTemperature: 16,
+		maxWeatherAge:        3 * time.Hour,
+		maxIndoorAge:         time.Hour,
+	}
+
+	for _, option := range options {
+		if err := option(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Celsius formats a temperature for the reasons of a recommendation in °C
+func Celsius(celsius float64) string {
+	return fmt.Sprintf("%.1f°C", celsius)
+}
+
+// Recommend returns a recommendation for every sensor based on its latest reading and the latest weather data.
+// formatTemperature formats the temperatures in the reasons in the units of the reader, nil uses Celsius.
+func (r *Recommender) Recommend(
+	formatTemperature func(celsius float64) string) ([]*contracts.VentilationRecommendation, error) {
+	if formatTemperature == nil {
+		formatTemperature = Celsius
+	}
+
+	readings, err := r.indoor.GetLatest()
+	if err != nil {
+		return nil, err
+	}
+
+	if r.registry != nil {
+		sensors, err := r.registry.GetEnabled()
+		if err != nil {
+			return nil, err
+		}
+		enabled := make(map[int]bool, len(sensors))
+		for _, sensor := range sensors {
+			enabled[sensor.ID] = true
+		}
+
+		filtered := make([]*contracts.SensorReading, 0, len(readings))
+		for _, reading := range readings {
+			if enabled[reading.SensorID] {
+				filtered = append(filtered, reading)
+			}
+		}
+		readings = filtered
+	}
+
+	weather, err := r.outdoor.GetLatest()
+	if err != nil {
+		return nil, err
+	}
+
+	var outdoor *contracts.WeatherData
+	for _, data := range weather {
+		if r.weatherLocation == "" || data.Name == r.weatherLocation {
+			outdoor = data
+			break
+		}
+	}
+
+	now := r.now()
+	recommendations := make([]*contracts.VentilationRecommendation, 0, len(readings))
+	for _, reading := range readings {
+		recommendations = append(recommendations, r.recommend(reading, outdoor, now, formatTemperature))
+	}
+
+	return recommendations, nil
+}
+
+func (r *Recommender) recommend(indoor *contracts.SensorReading, outdoor *contracts.WeatherData,
+	now time.Time, formatTemperature func(celsius float64) string) *contracts.VentilationRecommendation {
+	rec := &contracts.VentilationRecommendation{
+		SensorID:               indoor.SensorID,
+		Action:                 ActionUnknown,
+		IndoorTemperature:      indoor.Temperature,
+		IndoorHumidity:         indoor.Humidity,
+		IndoorAbsoluteHumidity: climate.Round(climate.AbsoluteHumidity(indoor.Temperature, indoor.Humidity), 2),
+		CreatedAt:              now,
+	}
+
+	if age := now.Sub(indoor.Timestamp); age > r.maxIndoorAge {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("Latest indoor reading is %s old", age.Round(time.Minute)))
+		return rec
+	}
+	if outdoor == nil {
+		rec.Reasons = append(rec.Reasons, "No outdoor weather data available")
+		return rec
+	}
+	if age := now.Sub(outdoor.Time); age > r.maxWeatherAge {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("Outdoor weather data is %s old", age.Round(time.Minute)))
+		return rec
+	}
+
+	rec.OutdoorTemperature = float64(outdoor.Temperature)
+	rec.OutdoorHumidity = float64(outdoor.Humidity)
+	rec.OutdoorAbsoluteHumidity = climate.Round(climate.AbsoluteHumidity(rec.OutdoorTemperature, rec.OutdoorHumidity), 2)
+	// once the air is exchanged the room holds the outdoor water content at the indoor temperature
+	rec.ExpectedHumidity = climate.Round(climate.RelativeHumidity(indoor.Temperature, rec.OutdoorAbsoluteHumidity), 1)
+
+	difference := rec.IndoorAbsoluteHumidity - rec.OutdoorAbsoluteHumidity
+	if difference < r.minDifference {
+		rec.Action = ActionDontVentilate
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf(
+			"Outdoor air holds %.1f g/m³ of water and indoor air %.1f g/m³, ventilating would not dry the room",
+			rec.OutdoorAbsoluteHumidity, rec.IndoorAbsoluteHumidity))
+		return rec
+	}
+
+	if indoor.Humidity <= r.targetHumidity {
+		rec.Action = ActionDontVentilate
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("Indoor humidity of %.0f%% is already at or below the target of %.0f%%",
+			indoor.Humidity, r.targetHumidity))
+		return rec
+	}
+
+	rec.Action = ActionVentilate
+	rec.Minutes = duration(rec.OutdoorTemperature)
+	rec.Reasons = append(rec.Reasons,
+		fmt.Sprintf("Outdoor air is drier by %.1f g/m³", difference),
+		fmt.Sprintf("Exchanging the air lowers the humidity from %.0f%% to about %.0f%%",
+			indoor.Humidity, rec.ExpectedHumidity),
+		fmt.Sprintf("At %s outside the air is exchanged within about %d minutes",
+			formatTemperature(rec.OutdoorTemperature), rec.Minutes))
+
+	if critical := climate.CriticalHumidity(indoor.Temperature); indoor.Humidity >= critical {
+		rec.Urgent = true
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("Humidity is above the critical %.0f%% for mold growth at %s",
+			critical, formatTemperature(indoor.Temperature)))
+	}
+
+	if indoor.Temperature < r.minIndoorTemperature {
+		rec.Minutes = min(rec.Minutes, 5)
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("The room is already cold (%s), keep it short",
+			formatTemperature(indoor.Temperature)))
+	} else if rec.OutdoorTemperature > indoor.Temperature {
+		rec.Reasons = append(rec.Reasons, fmt.Sprintf("Outdoor air is warmer (%s), the room will warm up",
+			formatTemperature(rec.OutdoorTemperature)))
+	}
+
+	return rec
+}
+
+// duration returns the minutes of a full window opening needed to exchange the air of a room at the outdoor
+// temperature. It assumes a heated room: the colder the outdoor air, the faster it is exchanged.
+func duration(outdoorTemperature float64) int {
+	switch {
+	case outdoorTemperature <= 0:
+		return 5
+	case outdoorTemperature <= 5:
+		return 8
+	case outdoorTemperature <= 10:
+		return 12
+	case outdoorTemperature <= 15:
+		return 15
+	case outdoorTemperature <= 20:
+		return 20
+	default:
+		return 25
+	}
+}
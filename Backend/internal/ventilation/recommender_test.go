@@ -0,0 +1,176 @@
+package ventilation
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"testing"
+	"time"
+)
+
+type fakeIndoorRepository struct {
+	readings []*contracts.SensorReading
+}
+
+func (f *fakeIndoorRepository) GetLatest() ([]*contracts.SensorReading, error) {
+	return f.readings, nil
+}
+
+type fakeOutdoorRepository struct {
+	weather []*contracts.WeatherData
+}
+
+func (f *fakeOutdoorRepository) GetLatest() ([]*contracts.WeatherData, error) {
+	return f.weather, nil
+}
+
+type fakeSensorRegistry struct {
+	enabled []*contracts.Sensor
+}
+
+func (f *fakeSensorRegistry) GetEnabled() ([]*contracts.Sensor, error) {
+	return f.enabled, nil
+}
+
+func TestRecommend(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	weather := func(temperature, humidity float32) *contracts.WeatherData {
+		return &contracts.WeatherData{Name: "home", Time: now.Add(-30 * time.Minute), Temperature: temperature,
+			Humidity: humidity}
+	}
+
+	tests := []struct {
+		name        string
+		temperature float64
+		humidity    float64
+		// the age of the indoor reading
+		age         time.Duration
+		outdoor     *contracts.WeatherData
+		wantAction  string
+		wantMinutes int
+		wantUrgent  bool
+	}{
+		{name: "drier outside", temperature: 21, humidity: 65, outdoor: weather(5, 80), wantAction: ActionVentilate,
+			wantMinutes: 8},
+		{name: "above the critical humidity", temperature: 21, humidity: 85, outdoor: weather(5, 80),
+			wantAction: ActionVentilate, wantMinutes: 8, wantUrgent: true},
+		{name: "cold room", temperature: 15, humidity: 70, outdoor: weather(5, 80), wantAction: ActionVentilate,
+			wantMinutes: 5},
+		{name: "more humid outside", temperature: 21, humidity: 50, outdoor: weather(25, 80),
+			wantAction: ActionDontVentilate},
+		{name: "at the target humidity", temperature: 21, humidity: 45, outdoor: weather(0, 80),
+			wantAction: ActionDontVentilate},
+		{name: "no weather data", temperature: 21, humidity: 65, wantAction: ActionUnknown},
+		{
+			name:        "stale weather data",
+			temperature: 21,
+			humidity:    65,
+			outdoor:     &contracts.WeatherData{Time: now.Add(-4 * time.Hour), Temperature: 5, Humidity: 80},
+			wantAction:  ActionUnknown,
+		},
+		{name: "stale indoor reading", temperature: 21, humidity: 65, age: 2 * time.Hour, outdoor: weather(5, 80),
+			wantAction: ActionUnknown},
+		{name: "indoor reading at the maximum age", temperature: 21, humidity: 65, age: time.Hour,
+			outdoor: weather(5, 80), wantAction: ActionVentilate, wantMinutes: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRecommender(nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			indoor := &contracts.SensorReading{SensorID: 1, Temperature: tt.temperature, Humidity: tt.humidity,
+				Timestamp: now.Add(-tt.age)}
+
+			rec := r.recommend(indoor, tt.outdoor, now, Celsius)
+			if rec.Action != tt.wantAction {
+				t.Fatalf("action %s, want %s (reasons: %v)", rec.Action, tt.wantAction, rec.Reasons)
+			}
+			if rec.Minutes != tt.wantMinutes {
+				t.Errorf("%d minutes, want %d", rec.Minutes, tt.wantMinutes)
+			}
+			if rec.Urgent != tt.wantUrgent {
+				t.Errorf("urgent %v, want %v", rec.Urgent, tt.wantUrgent)
+			}
+			if len(rec.Reasons) == 0 {
+				t.Error("expected the reasons of the recommendation")
+			}
+		})
+	}
+}
+
+func TestRecommendSensors(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)
+	indoor := &fakeIndoorRepository{readings: []*contracts.SensorReading{
+		{SensorID: 1, Temperature: 21, Humidity: 65, Timestamp: now},
+		{SensorID: 2, Temperature: 21, Humidity: 65, Timestamp: now},
+	}}
+	outdoor := &fakeOutdoorRepository{weather: []*contracts.WeatherData{
+		{Name: "office", Time: now, Temperature: 25, Humidity: 80},
+		{Name: "home", Time: now, Temperature: 5, Humidity: 80},
+	}}
+
+	tests := []struct {
+		name     string
+		registry SensorRegistry
+		want     []int
+	}{
+		{name: "without a registry", want: []int{1, 2}},
+		{name: "disabled sensor", registry: &fakeSensorRegistry{enabled: []*contracts.Sensor{{ID: 2}}}, want: []int{2}},
+		{name: "no enabled sensors", registry: &fakeSensorRegistry{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := []Option{WithWeatherLocation("home")}
+			if tt.registry != nil {
+				options = append(options, WithSensorRegistry(tt.registry))
+			}
+			r, err := NewRecommender(indoor, outdoor, options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.now = func() time.Time { return now }
+
+			recommendations, err := r.Recommend(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recommendations) != len(tt.want) {
+				t.Fatalf("%d recommendations, want %d", len(recommendations), len(tt.want))
+			}
+			for i, rec := range recommendations {
+				if rec.SensorID != tt.want[i] {
+					t.Errorf("recommendation for sensor %d, want %d", rec.SensorID, tt.want[i])
+				}
+				// the weather of the configured location is drier
+				if rec.Action != ActionVentilate || rec.OutdoorTemperature != 5 {
+					t.Errorf("action %s at %.0f°C outside, want to ventilate at 5°C", rec.Action,
+						rec.OutdoorTemperature)
+				}
+			}
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		temperature float64
+		want        int
+	}{
+		{temperature: -10, want: 5},
+		{temperature: 0, want: 5},
+		{temperature: 0.1, want: 8},
+		{temperature: 5, want: 8},
+		{temperature: 10, want: 12},
+		{temperature: 15, want: 15},
+		{temperature: 20, want: 20},
+		{temperature: 20.1, want: 25},
+		{temperature: 35, want: 25},
+	}
+
+	for _, tt := range tests {
+		if got := duration(tt.temperature); got != tt.want {
+			t.Errorf("duration(%.1f) = %d, want %d", tt.temperature, got, tt.want)
+		}
+	}
+}
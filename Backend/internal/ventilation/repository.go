@@ -0,0 +1,194 @@
+package ventilation
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"time"
+)
+
+type AnalysisRepository interface {
+	SaveAll(analyses []*contracts.VentilationAnalysis) error
+	SaveSkipped(buttonReadingID int64, reason string, at time.Time) error
+	IsAnalyzed(buttonReadingID int64) (bool, error)
+	GetAll(offset int, limit int) ([]*contracts.VentilationAnalysis, error)
+	DeleteByButtonReading(buttonReadingID int64) error
+}
+
+type analysisRepository struct {
+	db *sql.DB
+}
+
+func NewAnalysisRepository(db *sql.DB) (AnalysisRepository, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	repo := &analysisRepository{db: db}
+	if err := repo.createTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *analysisRepository) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS ventilation_analyses (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		button_reading_id INTEGER NOT NULL,
+		button_id INTEGER NOT NULL,
+		sensor_id INTEGER NOT NULL,
+		start_at DATETIME NOT NULL,
+		end_at DATETIME NOT NULL,
+		before_temperature REAL NOT NULL,
+		before_humidity REAL NOT NULL,
+		before_absolute_humidity REAL NOT NULL,
+		during_temperature REAL NOT NULL,
+		during_humidity REAL NOT NULL,
+		during_absolute_humidity REAL NOT NULL,
+		after_temperature REAL,
+		after_humidity REAL,
+		after_absolute_humidity REAL,
+		absolute_humidity_drop REAL NOT NULL,
+		temperature_loss REAL NOT NULL,
+		recovery_minutes REAL,
+		created_at DATETIME NOT NULL,
+		UNIQUE (button_reading_id, sensor_id)
+	)`
+	if _, err := r.db.Exec(query); err != nil {
+		return err
+	}
+
+	// ventilations without a result, e.g. without readings, so they are not analyzed again on every pass
+	query = `
+	CREATE TABLE IF NOT EXISTS ventilation_analysis_skips (
+		button_reading_id INTEGER PRIMARY KEY,
+		reason TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	)`
+	_, err := r.db.Exec(query)
+	return err
+}
+
+// SaveAll stores the analyses of the sensors for a ventilation in a single transaction, so a ventilation is either
+// analyzed for all sensors or not at all. Existing analyses are replaced.
+func (r *analysisRepository) SaveAll(analyses []*contracts.VentilationAnalysis) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, analysis := range analyses {
+		if err := saveAnalysis(tx, analysis); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func saveAnalysis(tx *sql.Tx, analysis *contracts.VentilationAnalysis) error {
+	query := `INSERT OR REPLACE INTO ventilation_analyses (button_reading_id, button_id, sensor_id, start_at, end_at,
+	before_temperature, before_humidity, before_absolute_humidity,
+	during_temperature, during_humidity, during_absolute_humidity,
+	after_temperature, after_humidity, after_absolute_humidity,
+	absolute_humidity_drop, temperature_loss, recovery_minutes, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	var afterTemperature, afterHumidity, afterAbsoluteHumidity sql.NullFloat64
+	if analysis.After != nil {
+		afterTemperature = sql.NullFloat64{Float64: analysis.After.Temperature, Valid: true}
+		afterHumidity = sql.NullFloat64{Float64: analysis.After.Humidity, Valid: true}
+		afterAbsoluteHumidity = sql.NullFloat64{Float64: analysis.After.AbsoluteHumidity, Valid: true}
+	}
+	var recoveryMinutes sql.NullFloat64
+	if analysis.Recovered {
+		recoveryMinutes = sql.NullFloat64{Float64: analysis.RecoveryMinutes, Valid: true}
+	}
+
+	_, err := tx.Exec(query, analysis.ButtonReadingID, analysis.ButtonID, analysis.SensorID, analysis.StartAt,
+		analysis.EndAt, analysis.Before.Temperature, analysis.Before.Humidity, analysis.Before.AbsoluteHumidity,
+		analysis.During.Temperature, analysis.During.Humidity, analysis.During.AbsoluteHumidity,
+		afterTemperature, afterHumidity, afterAbsoluteHumidity,
+		analysis.AbsoluteHumidityDrop, analysis.TemperatureLoss, recoveryMinutes, analysis.CreatedAt)
+	return err
+}
+
+// SaveSkipped marks a ventilation that could not be analyzed, with the reason
+func (r *analysisRepository) SaveSkipped(buttonReadingID int64, reason string, at time.Time) error {
+	_, err := r.db.Exec(`INSERT OR REPLACE INTO ventilation_analysis_skips (button_reading_id, reason, created_at)
+	VALUES (?, ?, ?)`, buttonReadingID, reason, at)
+	return err
+}
+
+// IsAnalyzed reports whether a ventilation was analyzed or skipped
+func (r *analysisRepository) IsAnalyzed(buttonReadingID int64) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`SELECT (SELECT COUNT(*) FROM ventilation_analyses WHERE button_reading_id = ?) +
+	(SELECT COUNT(*) FROM ventilation_analysis_skips WHERE button_reading_id = ?)`, buttonReadingID, buttonReadingID).
+		Scan(&count)
+	return count > 0, err
+}
+
+// DeleteByButtonReading removes the analyses and the skip marker of a changed or deleted ventilation
+func (r *analysisRepository) DeleteByButtonReading(buttonReadingID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM ventilation_analyses WHERE button_reading_id = ?`, buttonReadingID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM ventilation_analysis_skips WHERE button_reading_id = ?`, buttonReadingID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll returns the analyses, newest ventilation first
+func (r *analysisRepository) GetAll(offset int, limit int) ([]*contracts.VentilationAnalysis, error) {
+	query := `SELECT id, button_reading_id, button_id, sensor_id, start_at, end_at,
+	before_temperature, before_humidity, before_absolute_humidity,
+	during_temperature, during_humidity, during_absolute_humidity,
+	after_temperature, after_humidity, after_absolute_humidity,
+	absolute_humidity_drop, temperature_loss, recovery_minutes, created_at
+	FROM ventilation_analyses ORDER BY start_at DESC, sensor_id LIMIT ? OFFSET ?`
+	return r.queryAnalyses(query, limit, offset)
+}
+
+func (r *analysisRepository) queryAnalyses(query string, args ...interface{}) ([]*contracts.VentilationAnalysis, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var analyses []*contracts.VentilationAnalysis
+	for rows.Next() {
+		var analysis contracts.VentilationAnalysis
+		var afterTemperature, afterHumidity, afterAbsoluteHumidity, recoveryMinutes sql.NullFloat64
+		err := rows.Scan(&analysis.ID, &analysis.ButtonReadingID, &analysis.ButtonID, &analysis.SensorID,
+			&analysis.StartAt, &analysis.EndAt,
+			&analysis.Before.Temperature, &analysis.Before.Humidity, &analysis.Before.AbsoluteHumidity,
+			&analysis.During.Temperature, &analysis.During.Humidity, &analysis.During.AbsoluteHumidity,
+			&afterTemperature, &afterHumidity, &afterAbsoluteHumidity,
+			&analysis.AbsoluteHumidityDrop, &analysis.TemperatureLoss, &recoveryMinutes, &analysis.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if afterTemperature.Valid {
+			analysis.After = &contracts.VentilationMetrics{
+				Temperature:      afterTemperature.Float64,
+				Humidity:         afterHumidity.Float64,
+				AbsoluteHumidity: afterAbsoluteHumidity.Float64,
+			}
+		}
+		analysis.Recovered = recoveryMinutes.Valid
+		analysis.RecoveryMinutes = recoveryMinutes.Float64
+		analyses = append(analyses, &analysis)
+	}
+
+	return analyses, rows.Err()
+}
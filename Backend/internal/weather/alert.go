@@ -0,0 +1,56 @@
+package weather
+
+import (
+	"BeRoHuTe/internal/alerts"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AlertEntry is the provider independent official weather warning, Start and End are Unix timestamps
+type AlertEntry struct {
+	Sender      string
+	Event       string
+	Start       int64
+	End         int64
+	Description string
+	Tags        []string
+}
+
+// the providers do not rate the warnings, the severity is derived from the wording of the event,
+// e.g. "Amtliche UNWETTERWARNUNG vor ORKANBÖEN" or "Wind advisory"
+var (
+	infoWords  = []string{"vorabinformation", "vorinformation", "advisory", "statement", "watch", "outlook"}
+	errorWords = []string{"unwetter", "extrem", "severe", "extreme", "orkan", "hurricane", "tornado"}
+)
+
+// severity maps the event of the warning to the severity of the alert bus
+func (e AlertEntry) severity() string {
+	event := strings.ToLower(e.Event)
+	for _, word := range infoWords {
+		if strings.Contains(event, word) {
+			return alerts.SeverityInfo
+		}
+	}
+	for _, word := range errorWords {
+		if strings.Contains(event, word) {
+			return alerts.SeverityError
+		}
+	}
+	return alerts.SeverityWarning
+}
+
+// validate rejects warnings without event or with an invalid period
+func (e AlertEntry) validate() error {
+	var err error
+	switch {
+	case strings.TrimSpace(e.Event) == "":
+		err = errors.New("weather alert without event")
+	case e.Start <= 0 || e.End < e.Start:
+		err = fmt.Errorf("weather alert %q with invalid period %d-%d", e.Event, e.Start, e.End)
+	}
+	if err != nil {
+		return &ProviderError{Kind: ErrInvalid, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package weather
+
+import (
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"time"
+)
+
+type AlertRepository interface {
+	Save(name string, alerts []contracts.WeatherAlert, fetchedAt time.Time) error
+	GetActive(at time.Time) ([]*contracts.WeatherAlert, error)
+}
+
+type alertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) (AlertRepository, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	repo := &alertRepository{db: db}
+	if err := repo.createTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (a *alertRepository) createTable() error {
+	// the same warning is returned with every fetch until it ends, it is identified by sender, event and start
+	query := `
+	CREATE TABLE IF NOT EXISTS weather_alerts (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    name TEXT NOT NULL,
+	    sender TEXT NOT NULL,
+	    event TEXT NOT NULL,
+	    severity TEXT NOT NULL,
+	    start_at DATETIME NOT NULL,
+	    end_at DATETIME NOT NULL,
+	    description TEXT NOT NULL,
+	    fetched_at DATETIME NOT NULL,
+	    UNIQUE(name, sender, event, start_at)
+	);
+	CREATE INDEX IF NOT EXISTS idx_weather_alerts_end_at ON weather_alerts(end_at);
+	`
+	_, err := a.db.Exec(query)
+	return err
+}
+
+// Save stores the warnings of a fetch for the location in a single transaction. Known warnings are updated,
+// warnings of the location that are withdrawn before their end, i.e. missing in the fetch, end at fetchedAt.
+func (a *alertRepository) Save(name string, alerts []contracts.WeatherAlert, fetchedAt time.Time) error {
+	fetchedAt = fetchedAt.Local()
+
+	tx, err := a.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO weather_alerts 
+	(name, sender, event, severity, start_at, end_at, description, fetched_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(name, sender, event, start_at) DO UPDATE SET 
+	severity = excluded.severity, end_at = excluded.end_at, description = excluded.description, 
+	fetched_at = excluded.fetched_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, alert := range alerts {
+		_, err := stmt.Exec(name, alert.Sender, alert.Event, alert.Severity, alert.StartAt.Local(),
+			alert.EndAt.Local(), alert.Description, fetchedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE weather_alerts SET end_at = ? WHERE name = ? AND end_at > ? AND fetched_at < ?`,
+		fetchedAt, name, fetchedAt, fetchedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetActive returns the warnings of all locations that did not end at the given time, including upcoming ones,
+// ordered by start
+func (a *alertRepository) GetActive(at time.Time) ([]*contracts.WeatherAlert, error) {
+	query := `SELECT id, name, sender, event, severity, start_at, end_at, description, fetched_at
+	FROM weather_alerts WHERE end_at > ? ORDER BY start_at, name`
+	rows, err := a.db.Query(query, at.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*contracts.WeatherAlert
+	for rows.Next() {
+		var alert contracts.WeatherAlert
+		err := rows.Scan(&alert.ID, &alert.Name, &alert.Sender, &alert.Event, &alert.Severity, &alert.StartAt,
+			&alert.EndAt, &alert.Description, &alert.FetchedAt)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, &alert)
+	}
+
+	return alerts, rows.Err()
+}
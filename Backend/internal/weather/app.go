@@ -3,22 +3,107 @@ package weather
 import (
 	"BeRoHuTe/internal/contracts"
 	"context"
+	"errors"
 	"log"
+	"sync"
 	"time"
 )
 
+type AppOption func(*App) error
+
+// WithBackoff sets the wait before the first retry of a failed fetch, it doubles with every further failure
+// up to max. A max of zero uses the fetch interval.
+func WithBackoff(initial, max time.Duration) AppOption {
+	return func(a *App) error {
+		if initial <= 0 || max < 0 {
+			return errors.New("invalid weather backoff")
+		}
+		a.initialBackoff = initial
+		a.maxBackoff = max
+		return nil
+	}
+}
+
+// WithForecasts stores the forecasts of providers offering them with every fetch,
+// forecasts fetched longer than the retention ago are deleted
+func WithForecasts(repo ForecastRepository, retention time.Duration) AppOption {
+	return func(a *App) error {
+		if retention <= 0 {
+			return errors.New("forecast retention must be positive")
+		}
+		a.forecastRepo = repo
+		a.forecastRetention = retention
+		return nil
+	}
+}
+
+// WithAlerts stores the official weather warnings of providers offering them with every fetch
+func WithAlerts(repo AlertRepository) AppOption {
+	return func(a *App) error {
+		a.alertRepo = repo
+		return nil
+	}
+}
+
+// App fetches the weather of a single location and stores it under the location name
 type App struct {
+	name    string
 	service Service
 	repo    WeatherRepository
 	start   bool
 	stop    chan bool
+
+	forecastRepo      ForecastRepository
+	forecastRetention time.Duration
+
+	alertRepo AlertRepository
+	// raised are the active warnings the OnAlert functions were called for, by ID
+	raised map[int64]contracts.WeatherAlert
+
+	mu           sync.Mutex
+	onAlert      []func(alert contracts.WeatherAlert)
+	onAlertEnded []func(alert contracts.WeatherAlert)
+
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
 }
 
-func NewApp(s Service, r WeatherRepository) *App {
-	return &App{
-		service: s,
-		repo:    r,
+func NewApp(name string, s Service, r WeatherRepository, options ...AppOption) (*App, error) {
+	if name == "" {
+		return nil, errors.New("missing weather location name")
 	}
+
+	app := &App{
+		name:           name,
+		service:        s,
+		repo:           r,
+		initialBackoff: 2 * time.Second,
+		raised:         make(map[int64]contracts.WeatherAlert),
+	}
+
+	for _, option := range options {
+		if err := option(app); err != nil {
+			return nil, err
+		}
+	}
+
+	return app, nil
+}
+
+// OnAlert registers a function called once for every active or upcoming weather warning of the location
+func (a *App) OnAlert(fn func(alert contracts.WeatherAlert)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onAlert = append(a.onAlert, fn)
+}
+
+// OnAlertEnded registers a function called when a warning passed to the OnAlert functions ended or was withdrawn
+func (a *App) OnAlertEnded(fn func(alert contracts.WeatherAlert)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onAlertEnded = append(a.onAlertEnded, fn)
 }
 
 func (a *App) Start(ctx context.Context, dur time.Duration) {
@@ -28,52 +113,93 @@ func (a *App) Start(ctx context.Context, dur time.Duration) {
 	a.start = true
 	a.stop = make(chan bool)
 
-	ticker := time.NewTicker(1 * time.Millisecond) // directly call it
+	timer := time.NewTimer(0) // directly call it
 
 	go func() {
-		defer ticker.Stop()
+		defer timer.Stop()
 
-		errors := 0
+		failures := 0
 
 		for {
 			select {
 			case <-a.stop:
+				return
 			case <-ctx.Done():
 				return
-			case <-ticker.C:
-				if errors > 5 {
-					ticker.Reset(dur)
-					log.Println("Weather app timeout")
-					errors = 0
-					continue
+			case <-timer.C:
+				report, err := a.fetch()
+				if err == nil {
+					err = a.storeCurrentWeather(report.Current)
 				}
-
-				err := a.fetchAndStoreCurrentWeatherDetails()
 				if err != nil {
-					log.Println("WeatherApp error: ", err)
-					errors++
-					ticker.Reset(2 * time.Second)
+					failures++
+					wait := a.retryDelay(err, failures, dur)
+					log.Printf("WeatherApp %s error (attempt %d), retrying in %v: %v", a.name, failures, wait, err)
+					timer.Reset(wait)
 					continue
 				}
 
-				errors = 0
-				ticker.Reset(dur)
-			default:
+				failures = 0
+				timer.Reset(dur)
+
+				// a failed forecast or alert list is fetched again with the next current weather
+				if err := a.storeForecast(report); err != nil {
+					log.Printf("WeatherApp %s forecast error: %v", a.name, err)
+				}
+				if err := a.storeAlerts(report); err != nil {
+					log.Printf("WeatherApp %s alerts error: %v", a.name, err)
+				}
 			}
 		}
 	}()
 }
 
-func (a *App) fetchAndStoreCurrentWeatherDetails() error {
+// retryDelay returns the wait before the next fetch after the given number of consecutive failures
+func (a *App) retryDelay(err error, failures int, interval time.Duration) time.Duration {
+	maxBackoff := a.maxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = interval
+	}
+
+	var providerErr *ProviderError
+	switch {
+	case errors.Is(err, ErrAuth):
+		// retrying does not help until the credentials are fixed
+		return interval
+	case errors.As(err, &providerErr) && providerErr.RetryAfter > 0:
+		return min(providerErr.RetryAfter, max(maxBackoff, interval))
+	}
+
+	delay := a.initialBackoff
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+// fetch returns the current weather and the forecast and alerts the provider offers, providers implementing
+// ReportService return all of them with a single request
+func (a *App) fetch() (*Report, error) {
+	if reporter, ok := a.service.(ReportService); ok {
+		return reporter.GetReport()
+	}
+
 	details, err := a.service.GetCurrentWeatherDetails()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	report := &Report{Current: details}
+	if forecaster, ok := a.service.(ForecastService); ok && a.forecastRepo != nil {
+		report.Forecast, report.ForecastErr = forecaster.GetForecast()
 	}
+	return report, nil
+}
 
+func (a *App) storeCurrentWeather(details *CurrentWeather) error {
 	// save to repository
-	err = a.repo.Save(contracts.WeatherData{
+	err := a.repo.Save(contracts.WeatherData{
 		Time:        time.Unix(details.Timestamp, 0),
-		Name:        "Home",
+		Name:        a.name,
 		Latitude:    details.Latitude,
 		Longitude:   details.Longitude,
 		Temperature: details.Temperature,
@@ -87,6 +213,118 @@ func (a *App) fetchAndStoreCurrentWeatherDetails() error {
 	return nil
 }
 
+func (a *App) storeForecast(report *Report) error {
+	if report.ForecastErr != nil {
+		return report.ForecastErr
+	}
+	forecast := report.Forecast
+	if forecast == nil || a.forecastRepo == nil {
+		return nil
+	}
+
+	fetchedAt := time.Now()
+	forecasts := append(a.toForecasts(contracts.ResolutionHourly, forecast.Hourly, fetchedAt),
+		a.toForecasts(contracts.ResolutionDaily, forecast.Daily, fetchedAt)...)
+	if err := a.forecastRepo.Save(forecasts); err != nil {
+		return err
+	}
+
+	deleted, err := a.forecastRepo.DeleteFetchedBefore(a.name, fetchedAt.Add(-a.forecastRetention))
+	if err != nil {
+		return err
+	}
+	if deleted > 0 {
+		log.Printf("WeatherApp %s deleted %d old forecasts", a.name, deleted)
+	}
+	return nil
+}
+
+func (a *App) toForecasts(resolution string, entries []ForecastEntry, fetchedAt time.Time) []contracts.WeatherForecast {
+	forecasts := make([]contracts.WeatherForecast, 0, len(entries))
+	for _, entry := range entries {
+		forecasts = append(forecasts, contracts.WeatherForecast{
+			Name:                     a.name,
+			Resolution:               resolution,
+			FetchedAt:                fetchedAt,
+			Time:                     time.Unix(entry.Timestamp, 0),
+			Temperature:              entry.Temperature,
+			TemperatureMin:           entry.TemperatureMin,
+			TemperatureMax:           entry.TemperatureMax,
+			Humidity:                 entry.Humidity,
+			PrecipitationProbability: entry.PrecipitationProbability,
+		})
+	}
+	return forecasts
+}
+
+// storeAlerts stores the current warnings and notifies about new and ended ones
+func (a *App) storeAlerts(report *Report) error {
+	if report.AlertsErr != nil {
+		return report.AlertsErr
+	}
+	entries := report.Alerts
+	if entries == nil || a.alertRepo == nil {
+		return nil
+	}
+
+	fetchedAt := time.Now()
+	alerts := make([]contracts.WeatherAlert, 0, len(entries))
+	for _, entry := range entries {
+		alerts = append(alerts, contracts.WeatherAlert{
+			Name:        a.name,
+			Sender:      entry.Sender,
+			Event:       entry.Event,
+			Severity:    entry.severity(),
+			StartAt:     time.Unix(entry.Start, 0),
+			EndAt:       time.Unix(entry.End, 0),
+			Description: entry.Description,
+		})
+	}
+	if err := a.alertRepo.Save(a.name, alerts, fetchedAt); err != nil {
+		return err
+	}
+
+	active, err := a.alertRepo.GetActive(fetchedAt)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	onAlert := append([]func(alert contracts.WeatherAlert){}, a.onAlert...)
+	onAlertEnded := append([]func(alert contracts.WeatherAlert){}, a.onAlertEnded...)
+	a.mu.Unlock()
+
+	current := make(map[int64]bool)
+	for _, alert := range active {
+		if alert.Name != a.name {
+			continue
+		}
+		current[alert.ID] = true
+		if _, ok := a.raised[alert.ID]; ok {
+			continue
+		}
+		a.raised[alert.ID] = *alert
+		log.Printf("WeatherApp %s alert %q from %v to %v", a.name, alert.Event, alert.StartAt, alert.EndAt)
+		for _, fn := range onAlert {
+			fn(*alert)
+		}
+	}
+	for id, alert := range a.raised {
+		if current[id] {
+			continue
+		}
+		delete(a.raised, id)
+		for _, fn := range onAlertEnded {
+			fn(alert)
+		}
+	}
+	return nil
+}
+
 func (a *App) Stop() {
-	a.stop <- true
+	if !a.start {
+		return
+	}
+	close(a.stop)
+	a.start = false
 }
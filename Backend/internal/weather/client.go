@@ -0,0 +1,160 @@
+package weather
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maximum size of a provider response
+const maxResponseSize = 1 << 20
+
+var (
+	ErrAuth      = errors.New("weather provider rejected the credentials")
+	ErrRateLimit = errors.New("weather provider rate limit exceeded")
+	ErrTransient = errors.New("weather provider temporarily unavailable")
+	ErrInvalid   = errors.New("invalid weather provider response")
+)
+
+// ProviderError is a failed request to a weather provider, errors.Is matches its kind (ErrAuth, ErrRateLimit,
+// ErrTransient or ErrInvalid)
+type ProviderError struct {
+	Kind       error
+	StatusCode int
+	// RetryAfter is the wait requested by the provider, zero if it requested none
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%v (status %d): %v", e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *ProviderError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// client fetches the JSON responses of a weather provider
+type client struct {
+	baseURL  string
+	http     *http.Client
+	timeout  time.Duration
+	language string
+}
+
+type ServiceOption func(*client)
+
+// WithBaseURL replaces the API endpoint of the provider, e.g. by a local stand-in serving recorded responses
+func WithBaseURL(baseURL string) ServiceOption {
+	return func(c *client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithLanguage sets the language of the texts of the provider, e.g. the weather alerts (default "de")
+func WithLanguage(language string) ServiceOption {
+	return func(c *client) {
+		if language = strings.ToLower(strings.TrimSpace(language)); language != "" {
+			c.language = language
+		}
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client, the timeout of WithTimeout still applies
+func WithHTTPClient(httpClient *http.Client) ServiceOption {
+	return func(c *client) {
+		c.http = httpClient
+	}
+}
+
+// WithTimeout limits the duration of a request including reading the response (default 10 seconds)
+func WithTimeout(timeout time.Duration) ServiceOption {
+	return func(c *client) {
+		c.timeout = timeout
+	}
+}
+
+func newClient(baseURL string, options ...ServiceOption) *client {
+	c := &client{
+		baseURL:  baseURL,
+		http:     &http.Client{},
+		timeout:  10 * time.Second,
+		language: "de",
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	// applied after all options, so it does not depend on their order; the passed client is not modified
+	httpClient := *c.http
+	httpClient.Timeout = c.timeout
+	c.http = &httpClient
+	return c
+}
+
+func (c *client) getJSON(path string, query url.Values, target any) error {
+	resp, err := c.http.Get(c.baseURL + path + "?" + query.Encode())
+	if err != nil {
+		// the error contains the URL, which contains the API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return &ProviderError{Kind: ErrTransient, Err: err}
+	}
+	defer resp.Body.Close()
+
+	body := io.LimitReader(resp.Body, maxResponseSize)
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(body, 512))
+		if len(strings.TrimSpace(string(message))) == 0 {
+			message = []byte(http.StatusText(resp.StatusCode))
+		}
+		providerErr := &ProviderError{
+			StatusCode: resp.StatusCode,
+			Err:        errors.New(strings.TrimSpace(string(message))),
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			providerErr.Kind = ErrAuth
+		case resp.StatusCode == http.StatusTooManyRequests:
+			providerErr.Kind = ErrRateLimit
+			providerErr.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout:
+			providerErr.Kind = ErrTransient
+			providerErr.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+		default:
+			providerErr.Kind = ErrInvalid
+		}
+		return providerErr
+	}
+
+	if err := json.NewDecoder(body).Decode(target); err != nil {
+		return &ProviderError{Kind: ErrInvalid, StatusCode: resp.StatusCode, Err: err}
+	}
+	return nil
+}
+
+// retryAfter parses the Retry-After header, given in seconds or as HTTP date
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && time.Until(at) > 0 {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,178 @@
+package weather
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer answers every request with the given status, headers and body and records the requests
+func newTestServer(t *testing.T, status int, header http.Header, body string) (*httptest.Server, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		for key, values := range header {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// readTestdata returns a recorded provider response
+func readTestdata(t *testing.T, name string) string {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("reading testdata %s: %v", name, err)
+	}
+	return string(body)
+}
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		header     http.Header
+		body       string
+		kind       error
+		retryAfter time.Duration
+	}{
+		{
+			name:   "unauthorized",
+			status: http.StatusUnauthorized,
+			body:   `{"cod":401,"message":"Invalid API key."}`,
+			kind:   ErrAuth,
+		},
+		{
+			name:   "forbidden",
+			status: http.StatusForbidden,
+			kind:   ErrAuth,
+		},
+		{
+			name:       "rate limit with retry after",
+			status:     http.StatusTooManyRequests,
+			header:     http.Header{"Retry-After": {"120"}},
+			body:       `{"cod":429,"message":"Your account is temporary blocked due to exceeding of requests limitation."}`,
+			kind:       ErrRateLimit,
+			retryAfter: 2 * time.Minute,
+		},
+		{
+			name:   "rate limit without retry after",
+			status: http.StatusTooManyRequests,
+			kind:   ErrRateLimit,
+		},
+		{
+			name:   "server error",
+			status: http.StatusInternalServerError,
+			body:   "internal error",
+			kind:   ErrTransient,
+		},
+		{
+			name:       "unavailable with retry after",
+			status:     http.StatusServiceUnavailable,
+			header:     http.Header{"Retry-After": {"30"}},
+			kind:       ErrTransient,
+			retryAfter: 30 * time.Second,
+		},
+		{
+			name:   "bad request",
+			status: http.StatusBadRequest,
+			body:   `{"error":true,"reason":"Latitude must be in range of -90 to 90°."}`,
+			kind:   ErrInvalid,
+		},
+		{
+			name:   "zero payload",
+			status: http.StatusOK,
+			body:   `{}`,
+			kind:   ErrInvalid,
+		},
+		{
+			name:   "malformed payload",
+			status: http.StatusOK,
+			body:   `{"current":`,
+			kind:   ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.status, tt.header, tt.body)
+			services := map[string]Service{
+				ProviderOpenWeather: NewOpenWeatherService("secret", 48.14, 11.58, WithBaseURL(server.URL)),
+				ProviderOpenMeteo:   NewOpenMeteoService(48.14, 11.58, WithBaseURL(server.URL)),
+			}
+			for provider, service := range services {
+				details, err := service.GetCurrentWeatherDetails()
+				if details != nil {
+					t.Errorf("%s: expected no weather, got %+v", provider, details)
+				}
+				if !errors.Is(err, tt.kind) {
+					t.Fatalf("%s: expected %v, got %v", provider, tt.kind, err)
+				}
+				var providerErr *ProviderError
+				if !errors.As(err, &providerErr) {
+					t.Fatalf("%s: expected a ProviderError, got %T", provider, err)
+				}
+				if providerErr.RetryAfter != tt.retryAfter {
+					t.Errorf("%s: expected retry after %v, got %v", provider, tt.retryAfter, providerErr.RetryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestTransportErrorHidesAPIKey(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, nil, "")
+	server.Close()
+
+	_, err := NewOpenWeatherService("secret", 48.14, 11.58, WithBaseURL(server.URL)).GetCurrentWeatherDetails()
+	if !errors.Is(err, ErrTransient) {
+		t.Fatalf("expected %v, got %v", ErrTransient, err)
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error contains the API key: %v", err)
+	}
+}
+
+func TestTimeoutIndependentOfOptionOrder(t *testing.T) {
+	httpClient := &http.Client{Timeout: time.Minute}
+	orders := map[string][]ServiceOption{
+		"timeout first": {WithTimeout(time.Second), WithHTTPClient(httpClient)},
+		"timeout last":  {WithHTTPClient(httpClient), WithTimeout(time.Second)},
+	}
+	for name, options := range orders {
+		c := newClient("", options...)
+		if c.http.Timeout != time.Second {
+			t.Errorf("%s: expected timeout %v, got %v", name, time.Second, c.http.Timeout)
+		}
+	}
+	if httpClient.Timeout != time.Minute {
+		t.Errorf("passed client was modified, timeout %v", httpClient.Timeout)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	service := NewOpenMeteoService(48.14, 11.58, WithBaseURL(server.URL), WithTimeout(50*time.Millisecond))
+	if _, err := service.GetCurrentWeatherDetails(); !errors.Is(err, ErrTransient) {
+		t.Fatalf("expected %v, got %v", ErrTransient, err)
+	}
+}
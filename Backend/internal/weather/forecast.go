@@ -0,0 +1,54 @@
+package weather
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ForecastEntry is the provider independent forecast for an hour or a day, Timestamp is its start
+type ForecastEntry struct {
+	Timestamp                int64
+	Temperature              float32
+	TemperatureMin           float32
+	TemperatureMax           float32
+	Humidity                 float32
+	PrecipitationProbability float32 // in %
+}
+
+type Forecast struct {
+	Hourly []ForecastEntry
+	Daily  []ForecastEntry
+}
+
+// ForecastService is implemented by the providers that offer forecasts
+type ForecastService interface {
+	GetForecast() (*Forecast, error)
+}
+
+// validate rejects empty forecasts and forecasts with implausible values
+func (f *Forecast) validate() error {
+	var err error
+	if len(f.Hourly) == 0 && len(f.Daily) == 0 {
+		err = errors.New("empty forecast")
+	}
+	for _, entries := range [][]ForecastEntry{f.Hourly, f.Daily} {
+		for _, entry := range entries {
+			if err != nil {
+				break
+			}
+			switch {
+			case entry.Timestamp <= 0:
+				err = errors.New("forecast without timestamp")
+			case entry.TemperatureMin < -90 || entry.TemperatureMax > 60:
+				err = fmt.Errorf("forecast temperature %.1f-%.1f°C out of range", entry.TemperatureMin,
+					entry.TemperatureMax)
+			case entry.Humidity < 0 || entry.Humidity > 100:
+				err = fmt.Errorf("forecast humidity %.1f%% out of range", entry.Humidity)
+			}
+		}
+	}
+	if err != nil {
+		return &ProviderError{Kind: ErrInvalid, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package weather
+
+import (
+	"BeRoHuTe/internal/climate"
+	"BeRoHuTe/internal/contracts"
+	"database/sql"
+	"time"
+)
+
+type ForecastRepository interface {
+	Save(forecasts []contracts.WeatherForecast) error
+	GetLatest(name string, resolution string, from time.Time) ([]*contracts.WeatherForecast, error)
+	DeleteFetchedBefore(name string, before time.Time) (int64, error)
+}
+
+type forecastRepository struct {
+	db *sql.DB
+}
+
+func NewForecastRepository(db *sql.DB) (ForecastRepository, error) {
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	repo := &forecastRepository{db: db}
+	if err := repo.createTable(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (f *forecastRepository) createTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS weather_forecasts (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    name TEXT NOT NULL,
+	    resolution TEXT NOT NULL,
+	    fetched_at DATETIME NOT NULL,
+	    time DATETIME NOT NULL,
+	    temperature REAL NOT NULL,
+	    temperature_min REAL NOT NULL,
+	    temperature_max REAL NOT NULL,
+	    humidity REAL NOT NULL,
+	    precipitation_probability REAL NOT NULL,
+	    UNIQUE(name, resolution, fetched_at, time)
+	);
+	CREATE INDEX IF NOT EXISTS idx_weather_forecasts_fetched_at ON weather_forecasts(resolution, fetched_at);
+	`
+	_, err := f.db.Exec(query)
+	return err
+}
+
+// Save stores the forecasts of a fetch in a single transaction
+func (f *forecastRepository) Save(forecasts []contracts.WeatherForecast) error {
+	tx, err := f.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO weather_forecasts
+	(name, resolution, fetched_at, time, temperature, temperature_min, temperature_max, humidity, precipitation_probability)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, forecast := range forecasts {
+		_, err := stmt.Exec(forecast.Name, forecast.Resolution, forecast.FetchedAt, forecast.Time, forecast.Temperature,
+			forecast.TemperatureMin, forecast.TemperatureMax, forecast.Humidity, forecast.PrecipitationProbability)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetLatest returns the forecasts of the latest fetch for the location starting at from, ordered by time
+func (f *forecastRepository) GetLatest(name string, resolution string, from time.Time) ([]*contracts.WeatherForecast, error) {
+	query := `SELECT id, name, resolution, fetched_at, time, temperature, temperature_min, temperature_max, humidity,
+	precipitation_probability
+	FROM weather_forecasts
+	WHERE name = ? AND resolution = ? AND time >= ?
+	AND fetched_at = (SELECT MAX(fetched_at) FROM weather_forecasts WHERE name = ? AND resolution = ?)
+	ORDER BY time`
+	return f.queryForecasts(query, name, resolution, from, name, resolution)
+}
+
+// DeleteFetchedBefore removes the forecasts of the location fetched before the given time and returns their number
+func (f *forecastRepository) DeleteFetchedBefore(name string, before time.Time) (int64, error) {
+	result, err := f.db.Exec(`DELETE FROM weather_forecasts WHERE name = ? AND fetched_at < ?`, name, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (f *forecastRepository) queryForecasts(query string, args ...interface{}) ([]*contracts.WeatherForecast, error) {
+	rows, err := f.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var forecasts []*contracts.WeatherForecast
+	for rows.Next() {
+		var forecast contracts.WeatherForecast
+		err := rows.Scan(&forecast.ID, &forecast.Name, &forecast.Resolution, &forecast.FetchedAt, &forecast.Time,
+			&forecast.Temperature, &forecast.TemperatureMin, &forecast.TemperatureMax, &forecast.Humidity,
+			&forecast.PrecipitationProbability)
+		if err != nil {
+			return nil, err
+		}
+		temperature, humidity := float64(forecast.Temperature), float64(forecast.Humidity)
+		forecast.DewPoint = float32(climate.Round(climate.DewPoint(temperature, humidity), 1))
+		forecast.AbsoluteHumidity = float32(climate.Round(climate.AbsoluteHumidity(temperature, humidity), 2))
+		forecasts = append(forecasts, &forecast)
+	}
+
+	return forecasts, rows.Err()
+}
@@ -0,0 +1,68 @@
+package weather
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLocation is the name of the location given by LOCATION_COORDS
+const DefaultLocation = "Home"
+
+// Location is a place whose weather is fetched every interval and stored under its name
+type Location struct {
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Interval  time.Duration
+}
+
+// ParseLocations parses the location definitions in the format "name:lat:lon[:interval_min],...",
+// locations without an interval are fetched every defaultInterval
+func ParseLocations(value string, defaultInterval time.Duration) ([]Location, error) {
+	var locations []Location
+	seen := map[string]bool{}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fields := strings.Split(entry, ":")
+		if len(fields) < 3 || len(fields) > 4 {
+			return nil, fmt.Errorf("invalid location definition %q, expected name:lat:lon[:interval_min]", entry)
+		}
+
+		location := Location{Name: strings.TrimSpace(fields[0]), Interval: defaultInterval}
+		if location.Name == "" {
+			return nil, fmt.Errorf("missing name in location %q", entry)
+		}
+		if seen[location.Name] {
+			return nil, fmt.Errorf("duplicate location %q", location.Name)
+		}
+		seen[location.Name] = true
+
+		var err error
+		location.Latitude, err = strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil || location.Latitude < -90 || location.Latitude > 90 {
+			return nil, fmt.Errorf("invalid latitude in %q", entry)
+		}
+		location.Longitude, err = strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+		if err != nil || location.Longitude < -180 || location.Longitude > 180 {
+			return nil, fmt.Errorf("invalid longitude in %q", entry)
+		}
+		if len(fields) > 3 && strings.TrimSpace(fields[3]) != "" {
+			minutes, err := strconv.Atoi(strings.TrimSpace(fields[3]))
+			if err != nil || minutes <= 0 {
+				return nil, fmt.Errorf("invalid interval in %q", entry)
+			}
+			location.Interval = time.Duration(minutes) * time.Minute
+		}
+
+		locations = append(locations, location)
+	}
+
+	return locations, nil
+}
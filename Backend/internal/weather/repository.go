@@ -1,26 +1,58 @@
 package weather
 
 import (
+	"BeRoHuTe/internal/climate"
 	"BeRoHuTe/internal/contracts"
 	"database/sql"
-	"log"
+	"errors"
+	"time"
 )
 
 type WeatherRepository interface {
 	Save(weather contracts.WeatherData) error
 	GetLatest() ([]*contracts.WeatherData, error)
+	GetInBetween(name string, start time.Time, end time.Time) ([]*contracts.WeatherData, error)
+	GetAggregates(name string, resolution string, start time.Time, end time.Time) ([]*contracts.WeatherAggregate, error)
+	GetAverageLastHour() (map[string]map[string]float64, error)
+	GetAverageToday() (map[string]map[string]float64, error)
+	GetAverageThisWeek() (map[string]map[string]float64, error)
+}
+
+type RepositoryOption func(*weatherRepository) error
+
+// WithLocations restricts the latest weather data to the configured locations, the data of removed locations
+// stays in the database but is no longer reported as current
+func WithLocations(names ...string) RepositoryOption {
+	return func(w *weatherRepository) error {
+		w.locations = make(map[string]bool, len(names))
+		for _, name := range names {
+			if name == "" {
+				return errors.New("location name must not be empty")
+			}
+			w.locations[name] = true
+		}
+		return nil
+	}
 }
 
 type weatherRepository struct {
 	db *sql.DB
+
+	// locations are the configured locations, nil for all locations
+	locations map[string]bool
 }
 
-func NewWeatherRepository(db *sql.DB) (WeatherRepository, error) {
+func NewWeatherRepository(db *sql.DB, options ...RepositoryOption) (WeatherRepository, error) {
 	if err := db.Ping(); err != nil {
 		return nil, err
 	}
 
 	repo := &weatherRepository{db: db}
+	for _, option := range options {
+		if err := option(repo); err != nil {
+			return nil, err
+		}
+	}
 	if err := repo.createTable(); err != nil {
 		return nil, err
 	}
@@ -37,7 +69,8 @@ func (w *weatherRepository) createTable() error {
     longitude REAL,
     temperature REAL,
     humidity REAL,
-    feels_like REAL)`
+    feels_like REAL);
+	CREATE INDEX IF NOT EXISTS idx_weather_data_name_time ON weather_data(name, time);`
 	_, err := w.db.Exec(query)
 	return err
 }
@@ -48,15 +81,125 @@ func (w *weatherRepository) Save(weather contracts.WeatherData) error {
 	return err
 }
 
+// GetLatest returns the latest weather data of every configured location, ordered by name
 func (w *weatherRepository) GetLatest() ([]*contracts.WeatherData, error) {
-	query := `SELECT id, time, name, latitude, longitude, temperature,humidity,feels_like FROM weather_data ORDER BY time DESC LIMIT 1`
-	rows, err := w.db.Query(query)
+	query := `SELECT id, time, name, latitude, longitude, temperature, humidity, feels_like FROM weather_data
+	WHERE id IN (
+		SELECT (SELECT id FROM weather_data latest WHERE latest.name = locations.name ORDER BY time DESC, id DESC LIMIT 1)
+		FROM (SELECT DISTINCT name FROM weather_data) locations)
+	ORDER BY name`
+	latest, err := w.queryReadings(query)
+	if err != nil || w.locations == nil {
+		return latest, err
+	}
+
+	configured := latest[:0]
+	for _, data := range latest {
+		if w.locations[data.Name] {
+			configured = append(configured, data)
+		}
+	}
+	return configured, nil
+}
+
+// GetInBetween returns the weather data of the location, or of all locations without a name, ordered by time
+func (w *weatherRepository) GetInBetween(name string, start time.Time, end time.Time) ([]*contracts.WeatherData, error) {
+	query := `SELECT id, time, name, latitude, longitude, temperature, humidity, feels_like FROM weather_data
+	WHERE (? = '' OR name = ?) AND time >= ? AND time <= ?
+	ORDER BY time, name`
+	return w.queryReadings(query, name, name, start.Local(), end.Local())
+}
+
+// GetAggregates returns the minimum, average and maximum temperature and humidity per hour or day of the location,
+// or of all locations without a name, ordered by time
+func (w *weatherRepository) GetAggregates(name string, resolution string, start time.Time,
+	end time.Time) ([]*contracts.WeatherAggregate, error) {
+	// times are stored as text, the first 13 characters are the date and hour, the first 10 the date
+	var length int
+	var layout string
+	switch resolution {
+	case contracts.ResolutionHourly:
+		length, layout = 13, "2006-01-02 15"
+	case contracts.ResolutionDaily:
+		length, layout = 10, "2006-01-02"
+	default:
+		return nil, errors.New("invalid resolution " + resolution)
+	}
+
+	// the rows are aggregated here, as the absolute humidity is averaged over the single readings
+	query := `SELECT name, substr(time, 1, ?) as period, temperature, humidity
+	FROM weather_data
+	WHERE (? = '' OR name = ?) AND time >= ? AND time <= ?
+	ORDER BY period, name`
+	rows, err := w.db.Query(query, length, name, name, start.Local(), end.Local())
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	return w.queryReadings(query)
+	var aggregates []*contracts.WeatherAggregate
+	var averages []*climate.Average
+	for rows.Next() {
+		var location, period string
+		var temperature, humidity float32
+		if err := rows.Scan(&location, &period, &temperature, &humidity); err != nil {
+			return nil, err
+		}
+
+		// the rows of a period and location follow each other
+		last := len(aggregates) - 1
+		if last < 0 || aggregates[last].Name != location || aggregates[last].Time.Format(layout) != period {
+			periodStart, err := time.ParseInLocation(layout, period, time.Local)
+			if err != nil {
+				return nil, err
+			}
+			aggregates = append(aggregates, &contracts.WeatherAggregate{
+				Name:           location,
+				Time:           periodStart,
+				TemperatureMin: temperature,
+				TemperatureMax: temperature,
+				HumidityMin:    humidity,
+				HumidityMax:    humidity,
+			})
+			averages = append(averages, &climate.Average{})
+			last++
+		}
+
+		aggregate := aggregates[last]
+		aggregate.Count++
+		aggregate.TemperatureMin = min(aggregate.TemperatureMin, temperature)
+		aggregate.TemperatureMax = max(aggregate.TemperatureMax, temperature)
+		aggregate.HumidityMin = min(aggregate.HumidityMin, humidity)
+		aggregate.HumidityMax = max(aggregate.HumidityMax, humidity)
+		averages[last].Add(float64(temperature), float64(humidity))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, aggregate := range aggregates {
+		metrics := averages[i].Metrics()
+		aggregate.TemperatureAvg = float32(metrics.Temperature)
+		aggregate.HumidityAvg = float32(metrics.Humidity)
+		aggregate.AbsoluteHumidity = float32(metrics.AbsoluteHumidity)
+	}
+	return aggregates, nil
+}
+
+// GetAverageLastHour returns average temperature, humidity and derived metrics for each location in the last hour
+func (w *weatherRepository) GetAverageLastHour() (map[string]map[string]float64, error) {
+	return w.queryAverages(time.Now().Add(-time.Hour))
+}
+
+// GetAverageToday returns average temperature, humidity and derived metrics for each location today
+func (w *weatherRepository) GetAverageToday() (map[string]map[string]float64, error) {
+	year, month, day := time.Now().Date()
+	return w.queryAverages(time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+}
+
+// GetAverageThisWeek returns average temperature, humidity and derived metrics for each location in the last 7 days
+func (w *weatherRepository) GetAverageThisWeek() (map[string]map[string]float64, error) {
+	return w.queryAverages(time.Now().AddDate(0, 0, -7))
 }
 
 func (w *weatherRepository) queryReadings(query string, args ...interface{}) ([]*contracts.WeatherData, error) {
@@ -73,8 +216,43 @@ func (w *weatherRepository) queryReadings(query string, args ...interface{}) ([]
 		if err != nil {
 			return nil, err
 		}
+		temperature, humidity := float64(datum.Temperature), float64(datum.Humidity)
+		datum.DewPoint = float32(climate.Round(climate.DewPoint(temperature, humidity), 1))
+		datum.AbsoluteHumidity = float32(climate.Round(climate.AbsoluteHumidity(temperature, humidity), 2))
+		datum.HeatIndex = float32(climate.Round(climate.HeatIndex(temperature, humidity), 1))
 		data = append(data, &datum)
 	}
 
 	return data, rows.Err()
 }
+
+func (w *weatherRepository) queryAverages(since time.Time) (map[string]map[string]float64, error) {
+	query := `SELECT name, temperature, humidity FROM weather_data WHERE time >= ?`
+	rows, err := w.db.Query(query, since.Local())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	averages := make(map[string]*climate.Average)
+	for rows.Next() {
+		var name string
+		var temperature, humidity float64
+		if err := rows.Scan(&name, &temperature, &humidity); err != nil {
+			return nil, err
+		}
+		if averages[name] == nil {
+			averages[name] = &climate.Average{}
+		}
+		averages[name].Add(temperature, humidity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]float64, len(averages))
+	for name, average := range averages {
+		result[name] = average.Metrics().Map()
+	}
+	return result, nil
+}
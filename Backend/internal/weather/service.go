@@ -1,5 +1,17 @@
 package weather
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	ProviderOpenWeather = "openweather"
+	ProviderOpenMeteo   = "openmeteo"
+)
+
+// CurrentWeather is the provider independent current weather, each provider maps its response to it
 type CurrentWeather struct {
 	Latitude    float32 `json:"lat"`
 	Longitude   float32 `json:"lon"`
@@ -9,6 +21,54 @@ type CurrentWeather struct {
 	FeelsLike   float32 `json:"feels_like"`
 }
 
+// validate rejects responses without or with implausible values, e.g. the zeroed fields of an error response
+func (w *CurrentWeather) validate() error {
+	var err error
+	switch {
+	case w.Timestamp <= 0:
+		err = errors.New("missing timestamp")
+	case time.Unix(w.Timestamp, 0).After(time.Now().Add(time.Hour)):
+		err = fmt.Errorf("timestamp %d is in the future", w.Timestamp)
+	case w.Temperature < -90 || w.Temperature > 60:
+		err = fmt.Errorf("temperature %.1f°C out of range", w.Temperature)
+	case w.Humidity <= 0 || w.Humidity > 100:
+		err = fmt.Errorf("humidity %.1f%% out of range", w.Humidity)
+	}
+	if err != nil {
+		return &ProviderError{Kind: ErrInvalid, Err: err}
+	}
+	return nil
+}
+
 type Service interface {
 	GetCurrentWeatherDetails() (*CurrentWeather, error)
 }
+
+// NewService creates the service of a weather provider, the API key is only used by providers that need one
+func NewService(provider string, apiKey string, lat, long float64, options ...ServiceOption) (Service, error) {
+	switch provider {
+	case ProviderOpenWeather:
+		return NewOpenWeatherService(apiKey, lat, long, options...), nil
+	case ProviderOpenMeteo:
+		return NewOpenMeteoService(lat, long, options...), nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q, expected %s or %s", provider,
+			ProviderOpenWeather, ProviderOpenMeteo)
+	}
+}
+
+// Report is the result of a single request returning the current weather together with the forecast and the
+// official weather warnings. The optional parts failing does not fail the report, their error is kept instead.
+type Report struct {
+	Current     *CurrentWeather
+	Forecast    *Forecast
+	ForecastErr error
+	// Alerts is nil if the provider offers no warnings and empty if there are none
+	Alerts    []AlertEntry
+	AlertsErr error
+}
+
+// ReportService is implemented by the providers that return everything with one (billed) request
+type ReportService interface {
+	GetReport() (*Report, error)
+}
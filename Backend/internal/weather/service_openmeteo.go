@@ -0,0 +1,149 @@
+package weather
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"strconv"
+)
+
+// OpenMeteoService requests the current weather from the keyless Open-Meteo forecast API
+type OpenMeteoService struct {
+	client    *client
+	latitude  float64
+	longitude float64
+}
+
+func NewOpenMeteoService(lat, long float64, options ...ServiceOption) *OpenMeteoService {
+	return &OpenMeteoService{
+		client:    newClient("https://api.open-meteo.com", options...),
+		latitude:  lat,
+		longitude: long,
+	}
+}
+
+func (o *OpenMeteoService) currentWeatherQuery() url.Values {
+	queryParams := url.Values{}
+	queryParams.Set("latitude", strconv.FormatFloat(o.latitude, 'f', -1, 64))
+	queryParams.Set("longitude", strconv.FormatFloat(o.longitude, 'f', -1, 64))
+	queryParams.Set("current", "temperature_2m,relative_humidity_2m,apparent_temperature")
+	queryParams.Set("timeformat", "unixtime")
+
+	return queryParams
+}
+
+type OpenMeteoCurrentDetails struct {
+	Time                int64   `json:"time"`
+	Temperature         float32 `json:"temperature_2m"`
+	RelativeHumidity    float32 `json:"relative_humidity_2m"`
+	ApparentTemperature float32 `json:"apparent_temperature"`
+}
+
+type OpenMeteoDetails struct {
+	Latitude  float32                 `json:"latitude"`
+	Longitude float32                 `json:"longitude"`
+	Timezone  string                  `json:"timezone"`
+	Current   OpenMeteoCurrentDetails `json:"current"`
+}
+
+func (o *OpenMeteoService) GetCurrentWeatherDetails() (*CurrentWeather, error) {
+	var omDetails OpenMeteoDetails
+	if err := o.client.getJSON("/v1/forecast", o.currentWeatherQuery(), &omDetails); err != nil {
+		return nil, err
+	}
+
+	details := &CurrentWeather{
+		Latitude:    omDetails.Latitude,
+		Longitude:   omDetails.Longitude,
+		Timestamp:   omDetails.Current.Time,
+		Temperature: omDetails.Current.Temperature,
+		Humidity:    omDetails.Current.RelativeHumidity,
+		FeelsLike:   omDetails.Current.ApparentTemperature,
+	}
+	if err := details.validate(); err != nil {
+		return nil, err
+	}
+	log.Println("Fetching current weather details", details)
+
+	return details, nil
+}
+
+func (o *OpenMeteoService) forecastQuery() url.Values {
+	queryParams := url.Values{}
+	queryParams.Set("latitude", strconv.FormatFloat(o.latitude, 'f', -1, 64))
+	queryParams.Set("longitude", strconv.FormatFloat(o.longitude, 'f', -1, 64))
+	queryParams.Set("hourly", "temperature_2m,relative_humidity_2m,precipitation_probability")
+	queryParams.Set("daily", "temperature_2m_mean,temperature_2m_min,temperature_2m_max,relative_humidity_2m_mean,"+
+		"precipitation_probability_max")
+	queryParams.Set("forecast_hours", "48")
+	queryParams.Set("forecast_days", "8")
+	// the days start at local midnight
+	queryParams.Set("timezone", "auto")
+	queryParams.Set("timeformat", "unixtime")
+
+	return queryParams
+}
+
+// OpenMeteoForecast contains a list per variable, all of the length of Time
+type OpenMeteoForecast struct {
+	Hourly struct {
+		Time                     []int64   `json:"time"`
+		Temperature              []float32 `json:"temperature_2m"`
+		RelativeHumidity         []float32 `json:"relative_humidity_2m"`
+		PrecipitationProbability []float32 `json:"precipitation_probability"`
+	} `json:"hourly"`
+	Daily struct {
+		Time                     []int64   `json:"time"`
+		Temperature              []float32 `json:"temperature_2m_mean"`
+		TemperatureMin           []float32 `json:"temperature_2m_min"`
+		TemperatureMax           []float32 `json:"temperature_2m_max"`
+		RelativeHumidity         []float32 `json:"relative_humidity_2m_mean"`
+		PrecipitationProbability []float32 `json:"precipitation_probability_max"`
+	} `json:"daily"`
+}
+
+// GetForecast returns the hourly forecast of the next 48 hours and the daily forecast of the next 8 days
+func (o *OpenMeteoService) GetForecast() (*Forecast, error) {
+	var omForecast OpenMeteoForecast
+	if err := o.client.getJSON("/v1/forecast", o.forecastQuery(), &omForecast); err != nil {
+		return nil, err
+	}
+
+	hourly, daily := omForecast.Hourly, omForecast.Daily
+	n := len(hourly.Time)
+	if len(hourly.Temperature) != n || len(hourly.RelativeHumidity) != n || len(hourly.PrecipitationProbability) != n {
+		return nil, &ProviderError{Kind: ErrInvalid, Err: errors.New("hourly forecast lists differ in length")}
+	}
+	n = len(daily.Time)
+	if len(daily.Temperature) != n || len(daily.TemperatureMin) != n || len(daily.TemperatureMax) != n ||
+		len(daily.RelativeHumidity) != n || len(daily.PrecipitationProbability) != n {
+		return nil, &ProviderError{Kind: ErrInvalid, Err: errors.New("daily forecast lists differ in length")}
+	}
+
+	forecast := &Forecast{}
+	for i, timestamp := range hourly.Time {
+		forecast.Hourly = append(forecast.Hourly, ForecastEntry{
+			Timestamp:                timestamp,
+			Temperature:              hourly.Temperature[i],
+			TemperatureMin:           hourly.Temperature[i],
+			TemperatureMax:           hourly.Temperature[i],
+			Humidity:                 hourly.RelativeHumidity[i],
+			PrecipitationProbability: hourly.PrecipitationProbability[i],
+		})
+	}
+	for i, timestamp := range daily.Time {
+		forecast.Daily = append(forecast.Daily, ForecastEntry{
+			Timestamp:                timestamp,
+			Temperature:              daily.Temperature[i],
+			TemperatureMin:           daily.TemperatureMin[i],
+			TemperatureMax:           daily.TemperatureMax[i],
+			Humidity:                 daily.RelativeHumidity[i],
+			PrecipitationProbability: daily.PrecipitationProbability[i],
+		})
+	}
+
+	if err := forecast.validate(); err != nil {
+		return nil, err
+	}
+	return forecast, nil
+}
@@ -0,0 +1,97 @@
+package weather
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestOpenMeteoCurrentWeather(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusOK, nil, readTestdata(t, "openmeteo_current.json"))
+
+	details, err := NewOpenMeteoService(48.137, 11.575, WithBaseURL(server.URL)).GetCurrentWeatherDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := CurrentWeather{
+		Latitude:    48.14,
+		Longitude:   11.58,
+		Timestamp:   1760680800,
+		Temperature: 8.4,
+		Humidity:    87,
+		FeelsLike:   6.1,
+	}
+	if *details != expected {
+		t.Errorf("expected %+v, got %+v", expected, *details)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(*requests))
+	}
+	request := (*requests)[0]
+	if request.URL.Path != "/v1/forecast" {
+		t.Errorf("unexpected path %s", request.URL.Path)
+	}
+	query := request.URL.Query()
+	for key, value := range map[string]string{"latitude": "48.137", "longitude": "11.575", "timeformat": "unixtime"} {
+		if query.Get(key) != value {
+			t.Errorf("expected %s=%s, got %q", key, value, query.Get(key))
+		}
+	}
+}
+
+func TestOpenMeteoForecast(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, nil, readTestdata(t, "openmeteo_forecast.json"))
+
+	forecast, err := NewOpenMeteoService(48.137, 11.575, WithBaseURL(server.URL)).GetForecast()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(forecast.Hourly) != 3 || len(forecast.Daily) != 2 {
+		t.Fatalf("expected 3 hourly and 2 daily entries, got %d and %d", len(forecast.Hourly), len(forecast.Daily))
+	}
+	expectedHour := ForecastEntry{
+		Timestamp:                1760684400,
+		Temperature:              9.1,
+		TemperatureMin:           9.1,
+		TemperatureMax:           9.1,
+		Humidity:                 84,
+		PrecipitationProbability: 15,
+	}
+	if forecast.Hourly[1] != expectedHour {
+		t.Errorf("expected %+v, got %+v", expectedHour, forecast.Hourly[1])
+	}
+	expectedDay := ForecastEntry{
+		Timestamp:                1760738400,
+		Temperature:              9.4,
+		TemperatureMin:           4.9,
+		TemperatureMax:           13.7,
+		Humidity:                 81,
+		PrecipitationProbability: 60,
+	}
+	if forecast.Daily[1] != expectedDay {
+		t.Errorf("expected %+v, got %+v", expectedDay, forecast.Daily[1])
+	}
+}
+
+func TestOpenMeteoForecastInvalid(t *testing.T) {
+	tests := map[string]string{
+		"lists differ in length": `{"hourly":{"time":[1760680800,1760684400],"temperature_2m":[8.4],
+			"relative_humidity_2m":[87,84],"precipitation_probability":[10,15]}}`,
+		"empty": `{}`,
+		"humidity out of range": `{"daily":{"time":[1760652000],"temperature_2m_mean":[10],"temperature_2m_min":[5],
+			"temperature_2m_max":[14],"relative_humidity_2m_mean":[180],"precipitation_probability_max":[35]}}`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, _ := newTestServer(t, http.StatusOK, nil, body)
+
+			forecast, err := NewOpenMeteoService(48.137, 11.575, WithBaseURL(server.URL)).GetForecast()
+			if forecast != nil || !errors.Is(err, ErrInvalid) {
+				t.Errorf("expected %v, got %+v, %v", ErrInvalid, forecast, err)
+			}
+		})
+	}
+}
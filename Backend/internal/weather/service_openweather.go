@@ -1,9 +1,8 @@
 package weather
 
 import (
-	"encoding/json"
 	"log"
-	"net/http"
+	"math"
 	"net/url"
 	"strconv"
 	"strings"
@@ -18,14 +17,16 @@ const (
 )
 
 type OpenWeatherService struct {
+	client    *client
 	apiKey    string
 	latitude  float64
 	longitude float64
 	excludes  []string
 }
 
-func NewOpenWeatherService(key string, lat, long float64) *OpenWeatherService {
+func NewOpenWeatherService(key string, lat, long float64, options ...ServiceOption) *OpenWeatherService {
 	return &OpenWeatherService{
+		client:    newClient("https://api.openweathermap.org", options...),
 		apiKey:    key,
 		latitude:  lat,
 		longitude: long,
@@ -33,16 +34,17 @@ func NewOpenWeatherService(key string, lat, long float64) *OpenWeatherService {
 	}
 }
 
-func (o *OpenWeatherService) currentWeatherLink() string {
+func (o *OpenWeatherService) currentWeatherQuery() url.Values {
 	queryParams := url.Values{}
 	queryParams.Set("appid", o.apiKey)
 	queryParams.Set("lat", strconv.FormatFloat(o.latitude, 'f', -1, 64))
 	queryParams.Set("lon", strconv.FormatFloat(o.longitude, 'f', -1, 64))
 	queryParams.Set("exclude", strings.Join(o.excludes, ","))
+	// values are stored in the canonical metric units and only converted for presentation
 	queryParams.Set("units", "metric")
-	queryParams.Set("lang", "de")
+	queryParams.Set("lang", o.client.language)
 
-	return "https://api.openweathermap.org/data/3.0/onecall?" + queryParams.Encode()
+	return queryParams
 }
 
 type OpenWeatherOneCallCurrentDetails struct {
@@ -60,28 +62,143 @@ type OpenWeatherOneCallDetails struct {
 }
 
 func (o *OpenWeatherService) GetCurrentWeatherDetails() (*CurrentWeather, error) {
-	uri := o.currentWeatherLink()
-
-	resp, err := http.Get(uri)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var owDetails OpenWeatherOneCallDetails
-	if err := json.NewDecoder(resp.Body).Decode(&owDetails); err != nil {
+	if err := o.client.getJSON("/data/3.0/onecall", o.currentWeatherQuery(), &owDetails); err != nil {
 		return nil, err
 	}
 
+	return owDetails.currentWeather()
+}
+
+func (d OpenWeatherOneCallDetails) currentWeather() (*CurrentWeather, error) {
 	details := &CurrentWeather{
-		Latitude:    owDetails.Lat,
-		Longitude:   owDetails.Lon,
-		Timestamp:   owDetails.Current.Timestamp,
-		Temperature: owDetails.Current.Temperature,
-		Humidity:    owDetails.Current.Humidity,
-		FeelsLike:   owDetails.Current.FeelsLike,
+		Latitude:    d.Lat,
+		Longitude:   d.Lon,
+		Timestamp:   d.Current.Timestamp,
+		Temperature: d.Current.Temperature,
+		Humidity:    d.Current.Humidity,
+		FeelsLike:   d.Current.FeelsLike,
+	}
+	if err := details.validate(); err != nil {
+		return nil, err
 	}
 	log.Println("Fetching current weather details", details)
 
 	return details, nil
 }
+
+// reportQuery requests everything except the minutely forecast, the One Call API bills every request
+func (o *OpenWeatherService) reportQuery() url.Values {
+	queryParams := o.currentWeatherQuery()
+	queryParams.Set("exclude", OwExcludeMinutely)
+
+	return queryParams
+}
+
+type OpenWeatherOneCallHourlyDetails struct {
+	Timestamp   int64   `json:"dt"`
+	Temperature float32 `json:"temp"`
+	Humidity    float32 `json:"humidity"`
+	Pop         float32 `json:"pop"`
+}
+
+type OpenWeatherOneCallDailyDetails struct {
+	Timestamp   int64 `json:"dt"`
+	Temperature struct {
+		Day float32 `json:"day"`
+		Min float32 `json:"min"`
+		Max float32 `json:"max"`
+	} `json:"temp"`
+	Humidity float32 `json:"humidity"`
+	Pop      float32 `json:"pop"`
+}
+
+type OpenWeatherOneCallAlert struct {
+	SenderName  string   `json:"sender_name"`
+	Event       string   `json:"event"`
+	Start       int64    `json:"start"`
+	End         int64    `json:"end"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+type OpenWeatherOneCallReport struct {
+	OpenWeatherOneCallDetails
+	Hourly []OpenWeatherOneCallHourlyDetails `json:"hourly"`
+	Daily  []OpenWeatherOneCallDailyDetails  `json:"daily"`
+	// missing in the response without warnings
+	Alerts []OpenWeatherOneCallAlert `json:"alerts"`
+}
+
+// GetReport returns the current weather, the hourly forecast of the next 48 hours, the daily forecast of the
+// next 8 days and the official weather warnings for the location with a single request
+func (o *OpenWeatherService) GetReport() (*Report, error) {
+	var owReport OpenWeatherOneCallReport
+	if err := o.client.getJSON("/data/3.0/onecall", o.reportQuery(), &owReport); err != nil {
+		return nil, err
+	}
+
+	current, err := owReport.currentWeather()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Current: current}
+	report.Forecast, report.ForecastErr = owReport.forecast()
+	report.Alerts, report.AlertsErr = owReport.alerts()
+	return report, nil
+}
+
+func (r OpenWeatherOneCallReport) forecast() (*Forecast, error) {
+	forecast := &Forecast{}
+	for _, hour := range r.Hourly {
+		forecast.Hourly = append(forecast.Hourly, ForecastEntry{
+			Timestamp:                hour.Timestamp,
+			Temperature:              hour.Temperature,
+			TemperatureMin:           hour.Temperature,
+			TemperatureMax:           hour.Temperature,
+			Humidity:                 hour.Humidity,
+			PrecipitationProbability: percent(hour.Pop),
+		})
+	}
+	for _, day := range r.Daily {
+		forecast.Daily = append(forecast.Daily, ForecastEntry{
+			Timestamp:                day.Timestamp,
+			Temperature:              day.Temperature.Day,
+			TemperatureMin:           day.Temperature.Min,
+			TemperatureMax:           day.Temperature.Max,
+			Humidity:                 day.Humidity,
+			PrecipitationProbability: percent(day.Pop),
+		})
+	}
+
+	if err := forecast.validate(); err != nil {
+		return nil, err
+	}
+	return forecast, nil
+}
+
+// percent converts the probability of precipitation to whole percent like Open-Meteo reports it
+func percent(pop float32) float32 {
+	return float32(math.Round(float64(pop) * 100))
+}
+
+// alerts returns an empty, not nil, list without warnings, as the provider offers them
+func (r OpenWeatherOneCallReport) alerts() ([]AlertEntry, error) {
+	alerts := make([]AlertEntry, 0, len(r.Alerts))
+	for _, alert := range r.Alerts {
+		entry := AlertEntry{
+			Sender:      alert.SenderName,
+			Event:       alert.Event,
+			Start:       alert.Start,
+			End:         alert.End,
+			Description: alert.Description,
+			Tags:        alert.Tags,
+		}
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, entry)
+	}
+	return alerts, nil
+}
@@ -0,0 +1,166 @@
+package weather
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestOpenWeatherCurrentWeather(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusOK, nil, readTestdata(t, "openweather_onecall.json"))
+
+	details, err := NewOpenWeatherService("secret", 48.1371, 11.5754, WithBaseURL(server.URL),
+		WithLanguage("EN")).GetCurrentWeatherDetails()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := CurrentWeather{
+		Latitude:    48.1371,
+		Longitude:   11.5754,
+		Timestamp:   1760680800,
+		Temperature: 8.62,
+		Humidity:    86,
+		FeelsLike:   6.95,
+	}
+	if *details != expected {
+		t.Errorf("expected %+v, got %+v", expected, *details)
+	}
+
+	query := (*requests)[0].URL.Query()
+	for key, value := range map[string]string{"appid": "secret", "units": "metric", "lang": "en"} {
+		if query.Get(key) != value {
+			t.Errorf("expected %s=%s, got %q", key, value, query.Get(key))
+		}
+	}
+	for _, part := range []string{OwExcludeHourly, OwExcludeDaily, OwExcludeAlerts, OwExcludeMinutely} {
+		if !strings.Contains(query.Get("exclude"), part) {
+			t.Errorf("expected %s to be excluded, got %q", part, query.Get("exclude"))
+		}
+	}
+}
+
+func TestOpenWeatherReport(t *testing.T) {
+	server, requests := newTestServer(t, http.StatusOK, nil, readTestdata(t, "openweather_onecall.json"))
+
+	report, err := NewOpenWeatherService("secret", 48.1371, 11.5754, WithBaseURL(server.URL)).GetReport()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("expected a single request, got %d", len(*requests))
+	}
+	if exclude := (*requests)[0].URL.Query().Get("exclude"); exclude != OwExcludeMinutely {
+		t.Errorf("expected only %s to be excluded, got %q", OwExcludeMinutely, exclude)
+	}
+
+	if report.Current.Timestamp != 1760680800 || report.Current.Temperature != 8.62 {
+		t.Errorf("unexpected current weather %+v", *report.Current)
+	}
+
+	if report.ForecastErr != nil {
+		t.Fatalf("unexpected forecast error: %v", report.ForecastErr)
+	}
+	if len(report.Forecast.Hourly) != 2 || len(report.Forecast.Daily) != 2 {
+		t.Fatalf("expected 2 hourly and 2 daily entries, got %d and %d", len(report.Forecast.Hourly),
+			len(report.Forecast.Daily))
+	}
+	expectedHour := ForecastEntry{
+		Timestamp:                1760684400,
+		Temperature:              9.35,
+		TemperatureMin:           9.35,
+		TemperatureMax:           9.35,
+		Humidity:                 82,
+		PrecipitationProbability: 35,
+	}
+	if report.Forecast.Hourly[1] != expectedHour {
+		t.Errorf("expected %+v, got %+v", expectedHour, report.Forecast.Hourly[1])
+	}
+	expectedDay := ForecastEntry{
+		Timestamp:                1760698800,
+		Temperature:              12.84,
+		TemperatureMin:           5.77,
+		TemperatureMax:           14.61,
+		Humidity:                 71,
+		PrecipitationProbability: 60,
+	}
+	if report.Forecast.Daily[0] != expectedDay {
+		t.Errorf("expected %+v, got %+v", expectedDay, report.Forecast.Daily[0])
+	}
+
+	if report.AlertsErr != nil {
+		t.Fatalf("unexpected alerts error: %v", report.AlertsErr)
+	}
+	if len(report.Alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(report.Alerts))
+	}
+	alert := report.Alerts[0]
+	if alert.Sender != "Deutscher Wetterdienst" || alert.Event != "Amtliche WARNUNG vor FROST" ||
+		alert.Start != 1760738400 || alert.End != 1760770800 ||
+		len(alert.Tags) != 1 || alert.Tags[0] != "Extreme low temperature" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+}
+
+func TestOpenWeatherReportPartsFailIndependently(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		forecastErr bool
+		alertsErr   bool
+	}{
+		{
+			name: "no alerts",
+			body: `{"current":{"dt":1760680800,"temp":8.6,"humidity":86},
+				"hourly":[{"dt":1760680800,"temp":8.6,"humidity":86,"pop":0.1}]}`,
+		},
+		{
+			name:        "no forecast",
+			body:        `{"current":{"dt":1760680800,"temp":8.6,"humidity":86},"alerts":[]}`,
+			forecastErr: true,
+		},
+		{
+			name: "alert without period",
+			body: `{"current":{"dt":1760680800,"temp":8.6,"humidity":86},
+				"hourly":[{"dt":1760680800,"temp":8.6,"humidity":86,"pop":0.1}],
+				"alerts":[{"sender_name":"DWD","event":"FROST"}]}`,
+			alertsErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, http.StatusOK, nil, tt.body)
+
+			report, err := NewOpenWeatherService("secret", 48.1371, 11.5754, WithBaseURL(server.URL)).GetReport()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.forecastErr != (report.ForecastErr != nil) || tt.forecastErr != (report.Forecast == nil) {
+				t.Errorf("forecast %+v, error %v", report.Forecast, report.ForecastErr)
+			}
+			if report.ForecastErr != nil && !errors.Is(report.ForecastErr, ErrInvalid) {
+				t.Errorf("expected %v, got %v", ErrInvalid, report.ForecastErr)
+			}
+			if tt.alertsErr {
+				if report.Alerts != nil || !errors.Is(report.AlertsErr, ErrInvalid) {
+					t.Errorf("expected %v, got %+v, %v", ErrInvalid, report.Alerts, report.AlertsErr)
+				}
+			} else if report.Alerts == nil || len(report.Alerts) != 0 || report.AlertsErr != nil {
+				// an empty list ends all active alerts, nil would keep them
+				t.Errorf("expected an empty alert list, got %+v, %v", report.Alerts, report.AlertsErr)
+			}
+		})
+	}
+}
+
+func TestOpenWeatherReportWithoutCurrentWeather(t *testing.T) {
+	server, _ := newTestServer(t, http.StatusOK, nil, `{"hourly":[{"dt":1760680800,"temp":8.6,"humidity":86}]}`)
+
+	report, err := NewOpenWeatherService("secret", 48.1371, 11.5754, WithBaseURL(server.URL)).GetReport()
+	if report != nil || !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected %v, got %+v, %v", ErrInvalid, report, err)
+	}
+}
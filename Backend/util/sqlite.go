@@ -0,0 +1,40 @@
+package util
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// AddColumnIfMissing adds a column to an existing table, sqlite does not support "ADD COLUMN IF NOT EXISTS"
+func AddColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	exists, err := HasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// HasColumn checks whether the table contains the given column
+func HasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
@@ -8,6 +8,18 @@ import json
 # Adjust host, port, and password as needed for your Redis instance
 r = redis.Redis(host='localhost', port=6379, db=0, decode_responses=True)
 
+# Besides the keys every reading is appended to this stream, so the backend can consume all of them
+# (SENSOR_SOURCE=redis-stream). The stream is trimmed to roughly the last STREAM_MAXLEN entries.
+STREAM = 'sensors'
+STREAM_MAXLEN = 100000
+
+
+def publish(key, data):
+    payload = json.dumps(data)
+    r.set(key, payload)
+    r.xadd(STREAM, {'sensor': key, 'data': payload}, maxlen=STREAM_MAXLEN, approximate=True)
+
+
 # Initialisieren Sie den DHT, wobei der Datenpin mit Pin 16
 # (GPIO 23) des Raspberry Pi verbunden ist:
 dhtDevice = adafruit_dht.DHT22(board.D23)
@@ -27,7 +39,7 @@ while True:
             'humidity': humidity,
             'timestamp': time.time()
         }
-        r.set('sensor1', json.dumps(sensor1_data))
+        publish('sensor1', sensor1_data)
 
         print("Sensor1: {:.1f} F / {:.1f} C Humidity: {}%".format(
             temperature_f, temperature_c, humidity))
@@ -44,7 +56,7 @@ while True:
             'humidity': humid2,
             'timestamp': time.time()
         }
-        r.set('sensor2', json.dumps(sensor2_data))
+        publish('sensor2', sensor2_data)
 
         print("Sensor2: {:.1f} F / {:.1f} C Humidity: {}%".format(
             temp_f2, temp_c2, humid2))